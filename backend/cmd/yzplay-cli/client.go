@@ -0,0 +1,18 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"yz-playground/pkg/api"
+)
+
+// addClientFlags registers the connection flags on a command and returns a
+// function creating the client they configure, to call once flags are parsed
+func addClientFlags(flags *flag.FlagSet) func() *api.Client {
+	server := flags.String("server", envOr("YZPLAY_SERVER", defaultServer), "playground backend URL (env YZPLAY_SERVER)")
+	apiKey := flags.String("api-key", os.Getenv("YZPLAY_API_KEY"), "API key (env YZPLAY_API_KEY)")
+	return func() *api.Client {
+		return api.NewClient(*server, api.ClientOptions{APIKey: *apiKey})
+	}
+}
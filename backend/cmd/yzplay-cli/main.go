@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// Defaults for the connection flags, overridable by environment variables
+const (
+	defaultServer   = "http://localhost:8080"
+	defaultFrontend = "http://localhost:3000/"
+)
+
+// commands maps subcommand names to their entry points
+var commands = map[string]func(args []string) int{
+	"run":   runRun,
+	"share": runShare,
+	"fetch": runFetch,
+}
+
+const usage = `yzplay-cli runs Yz code on a Yz Playground server from the terminal.
+
+Usage:
+  yzplay-cli <command> [flags] [arguments]
+
+Commands:
+  run <file.yz>     run a local Yz file on the server, streaming its output
+  share <file.yz>   print a playground link that opens the file in the editor
+  fetch <url>       save the code from a shared playground link
+
+Environment:
+  YZPLAY_SERVER     backend URL (default ` + defaultServer + `)
+  YZPLAY_API_KEY    API key sent with every request
+  YZPLAY_FRONTEND   playground URL used for shared links (default ` + defaultFrontend + `)
+
+Run "yzplay-cli <command> -h" for the flags of a command.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	name := os.Args[1]
+	if name == "-h" || name == "-help" || name == "--help" || name == "help" {
+		fmt.Print(usage)
+		return
+	}
+
+	command, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "yzplay-cli: unknown command %q\n\n%s", name, usage)
+		os.Exit(2)
+	}
+	os.Exit(command(os.Args[2:]))
+}
+
+// parseFlags parses a command's flags and reports whether the command should go on,
+// along with the exit code to use if not
+func parseFlags(flags *flag.FlagSet, args []string) (bool, int) {
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return false, 0
+		}
+		return false, 2
+	}
+	return true, 0
+}
+
+// usageError reports a misused command and returns the exit code
+func usageError(flags *flag.FlagSet, msg string) int {
+	fmt.Fprintf(os.Stderr, "%s: %s\n", flags.Name(), msg)
+	flags.Usage()
+	return 2
+}
+
+// fail reports a command failure and returns the exit code
+func fail(flags *flag.FlagSet, err error) int {
+	fmt.Fprintf(os.Stderr, "%s: %v\n", flags.Name(), err)
+	return 1
+}
+
+// envOr returns the environment variable name, or def if it's unset
+func envOr(name, def string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return def
+}
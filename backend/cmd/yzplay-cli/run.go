@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"yz-playground/pkg/api"
+)
+
+// runRun sends a local Yz file to the server, printing the program's output
+func runRun(args []string) int {
+	flags := flag.NewFlagSet("yzplay-cli run", flag.ContinueOnError)
+	newClient := addClientFlags(flags)
+	showGeneratedCode := flags.Bool("show-generated-code", false, "print the Go code generated by yzc")
+	timeout := flags.Int("timeout", 0, "execution time limit in milliseconds (default the server's limit)")
+	memory := flags.Int("memory", 0, "memory limit in MB (default the server's limit)")
+	noStream := flags.Bool("no-stream", false, "print the output once the program finishes instead of as it runs")
+	if ok, code := parseFlags(flags, args); !ok {
+		return code
+	}
+	if flags.NArg() != 1 {
+		return usageError(flags, "expected exactly one .yz file")
+	}
+
+	code, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		return fail(flags, err)
+	}
+
+	// Interrupting the client cancels the execution on the server
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	req := api.ExecuteRequest{
+		Code:              string(code),
+		Timeout:           *timeout,
+		Memory:            *memory,
+		ShowGeneratedCode: *showGeneratedCode,
+	}
+
+	c := newClient()
+	var result *api.ExecuteResponse
+	if *noStream {
+		result, err = c.Execute(ctx, req)
+		if err == nil && result.Output != "" {
+			fmt.Println(result.Output)
+		}
+	} else {
+		result, err = c.ExecuteStream(ctx, req, func(output string) {
+			fmt.Print(output)
+		})
+	}
+	if err != nil {
+		return fail(flags, err)
+	}
+
+	if result.Truncated && !*noStream {
+		// The marker is only part of the final result, not the streamed output
+		fmt.Fprintln(os.Stderr, "\n[output truncated]")
+	}
+	if *showGeneratedCode && result.GeneratedCode != "" {
+		fmt.Println("=== Generated Go Code ===")
+		fmt.Println(result.GeneratedCode)
+		fmt.Println("=== End Generated Code ===")
+	}
+
+	status := "success"
+	if !result.Success {
+		status = "failed"
+		if result.ErrorCode != "" {
+			status = string(result.ErrorCode)
+		}
+		fmt.Fprintln(os.Stderr, result.Error)
+	}
+	fmt.Fprintf(os.Stderr, "%s: total %dms, memory %dMB\n", status, result.ExecutionTime, result.MemoryUsed)
+
+	if !result.Success {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"yz-playground/pkg/api"
+)
+
+// runShare prints a playground link carrying the code of a local file, the
+// same kind of link the editor's Share button copies
+func runShare(args []string) int {
+	flags := flag.NewFlagSet("yzplay-cli share", flag.ContinueOnError)
+	frontend := flags.String("frontend", envOr("YZPLAY_FRONTEND", defaultFrontend), "playground URL to link to (env YZPLAY_FRONTEND)")
+	if ok, code := parseFlags(flags, args); !ok {
+		return code
+	}
+	if flags.NArg() != 1 {
+		return usageError(flags, "expected exactly one .yz file")
+	}
+
+	code, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		return fail(flags, err)
+	}
+
+	link, err := api.ShareURL(*frontend, string(code))
+	if err != nil {
+		return fail(flags, err)
+	}
+	fmt.Println(link)
+	return 0
+}
+
+// runFetch writes the code from a shared playground link to a file or stdout
+func runFetch(args []string) int {
+	flags := flag.NewFlagSet("yzplay-cli fetch", flag.ContinueOnError)
+	output := flags.String("o", "", "file to write the code to (default stdout)")
+	if ok, code := parseFlags(flags, args); !ok {
+		return code
+	}
+	if flags.NArg() != 1 {
+		return usageError(flags, "expected exactly one playground link")
+	}
+
+	code, err := api.SharedCode(flags.Arg(0))
+	if err != nil {
+		return fail(flags, err)
+	}
+	if !strings.HasSuffix(code, "\n") {
+		code += "\n"
+	}
+
+	if *output == "" {
+		fmt.Print(code)
+		return 0
+	}
+	if err := os.WriteFile(*output, []byte(code), 0644); err != nil {
+		return fail(flags, err)
+	}
+	fmt.Fprintf(os.Stderr, "Saved %s\n", *output)
+	return 0
+}
@@ -0,0 +1,704 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"yz-playground/internal/apierror"
+	"yz-playground/internal/auth"
+	"yz-playground/internal/config"
+	"yz-playground/internal/exercise"
+	"yz-playground/internal/health"
+	"yz-playground/internal/lint"
+	"yz-playground/internal/openapi"
+	"yz-playground/internal/sandbox"
+	"yz-playground/internal/yz"
+	"yz-playground/pkg/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+// legacyAPIPrefix serves the API at its original unversioned paths for existing clients
+const legacyAPIPrefix = "/api"
+
+// serviceName identifies the backend in health responses
+const serviceName = "yz-playground-backend"
+
+// apiServer holds what the API handlers need
+type apiServer struct {
+	settings  *config.Holder
+	manager   *sandbox.Manager
+	readiness *health.Checker
+	exercises *exercise.Store
+	keys      *auth.Store
+}
+
+// route is an API endpoint. Every route is served under each API prefix and
+// documented in the OpenAPI document.
+type route struct {
+	method  string
+	path    string // relative to the API prefix
+	keyed   bool   // whether the route goes through API key authentication
+	handler gin.HandlerFunc
+	doc     openapi.Operation
+}
+
+// routes lists the API endpoints
+func (s *apiServer) routes() []route {
+	return []route{
+		{http.MethodGet, "/health", false, s.liveness, openapi.Operation{
+			Summary:   "Liveness check, same as /health/live",
+			Responses: []openapi.Response{{Status: http.StatusOK, Body: api.HealthResponse{}}},
+		}},
+		{http.MethodGet, "/health/live", false, s.liveness, openapi.Operation{
+			Summary:   "Report that the process is up",
+			Responses: []openapi.Response{{Status: http.StatusOK, Body: api.HealthResponse{}}},
+		}},
+		{http.MethodGet, "/health/ready", false, s.ready, openapi.Operation{
+			Summary: "Check that executions can be served",
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: api.ReadinessResponse{}},
+				{Status: http.StatusServiceUnavailable, Description: "A check failed", Body: api.ReadinessResponse{}},
+			},
+		}},
+		{http.MethodGet, "/config", true, s.config, openapi.Operation{
+			Summary:   "Get the limits that apply to the caller",
+			Responses: withErrors([]openapi.Response{{Status: http.StatusOK, Body: api.ConfigResponse{}}}, keyedErrors...),
+			Secured:   true,
+		}},
+		{http.MethodGet, "/compiler/version", true, s.compilerVersion, openapi.Operation{
+			Summary:     "Get the sandbox's Yz compiler version",
+			Description: "Reports the default compiler, or the version named by the `compiler` query parameter.",
+			Responses: withErrors([]openapi.Response{{Status: http.StatusOK, Body: api.VersionResponse{}}},
+				slices.Concat(keyedErrors, []int{http.StatusBadRequest, http.StatusServiceUnavailable})...),
+			Secured: true,
+		}},
+		{http.MethodPost, "/execute", true, s.execute, openapi.Operation{
+			Summary: "Compile and run Yz code",
+			Request: api.ExecuteRequest{},
+			Responses: withErrors([]openapi.Response{{Status: http.StatusOK, Body: api.ExecuteResponse{}}},
+				slices.Concat(keyedErrors, executeErrors)...),
+			Secured: true,
+		}},
+		{http.MethodPost, "/execute/stream", true, s.executeStream, openapi.Operation{
+			Summary: "Compile and run Yz code, streaming its output",
+			Description: "Answers with server-sent events: `" + api.EventOutput + "` events carry an OutputEvent as the " +
+				"program prints, then a single `" + api.EventResult + "` event carries an ExecuteResponse, or an `" +
+				api.EventError + "` event carries an ErrorResponse if the sandbox failed.",
+			Request: api.ExecuteRequest{},
+			Responses: withErrors([]openapi.Response{{
+				Status:      http.StatusOK,
+				Description: "Event stream",
+				ContentType: "text/event-stream",
+				Schema:      map[string]any{"type": "string"},
+			}}, slices.Concat(keyedErrors, executeErrors)...),
+			Secured: true,
+		}},
+		{http.MethodPost, "/compile", true, s.compile, openapi.Operation{
+			Summary: "Compile Yz code without running it",
+			Request: api.CompileRequest{},
+			Responses: withErrors([]openapi.Response{{Status: http.StatusOK, Body: api.CompileResponse{}}},
+				slices.Concat(keyedErrors, executeErrors)...),
+			Secured: true,
+		}},
+		{http.MethodPost, "/benchmark", true, s.benchmark, openapi.Operation{
+			Summary: "Build Yz code once and time several runs of it",
+			Description: "Runs the built program the requested number of times in the sandbox and reports the " +
+				"minimum, median and 95th percentile of wall time, CPU time and peak memory. The timeout covers " +
+				"the build and every run; a run that fails ends the benchmark.",
+			Request: api.BenchmarkRequest{},
+			Responses: withErrors([]openapi.Response{{Status: http.StatusOK, Body: api.BenchmarkResponse{}}},
+				slices.Concat(keyedErrors, executeErrors)...),
+			Secured: true,
+		}},
+		{http.MethodPost, "/test", true, s.runTests, openapi.Operation{
+			Summary: "Run Yz tests against code",
+			Description: "Compiles the code together with the tests, top-level blocks named test, test_name or " +
+				"testName, and runs each in turn. A test passes unless it evaluates to false or the program " +
+				"stops while it runs; tests after one that stopped the program are skipped.",
+			Request: api.TestRequest{},
+			Responses: withErrors([]openapi.Response{{Status: http.StatusOK, Body: api.TestResponse{}}},
+				slices.Concat(keyedErrors, executeErrors)...),
+			Secured: true,
+		}},
+		{http.MethodGet, "/exercises", true, s.listExercises, openapi.Operation{
+			Summary: "List the exercises",
+			Responses: withErrors([]openapi.Response{{Status: http.StatusOK, Body: api.ExerciseListResponse{}}},
+				keyedErrors...),
+			Secured: true,
+		}},
+		{http.MethodGet, "/exercises/:id", true, s.getExercise, openapi.Operation{
+			Summary:     "Get an exercise",
+			Description: "Answers with the exercise's prompt, starter code, limits and sample cases; its other cases stay hidden.",
+			Responses: withErrors([]openapi.Response{{Status: http.StatusOK, Body: api.ExerciseResponse{}}},
+				slices.Concat(keyedErrors, []int{http.StatusNotFound})...),
+			Secured: true,
+		}},
+		{http.MethodPost, "/exercises/:id/submit", true, s.submitExercise, openapi.Operation{
+			Summary: "Submit a solution to an exercise",
+			Description: "Builds the code once and runs it on every case of the exercise, each within the " +
+				"exercise's limits, and answers with a verdict per case: AC, WA, TLE, MLE, RE or CE. Hidden " +
+				"cases' inputs and expected outputs are never returned.",
+			Request: api.SubmitRequest{},
+			Responses: withErrors([]openapi.Response{{Status: http.StatusOK, Body: api.SubmitResponse{}}},
+				slices.Concat(keyedErrors, executeErrors, []int{http.StatusNotFound})...),
+			Secured: true,
+		}},
+		{http.MethodPost, "/format", true, s.format, openapi.Operation{
+			Summary: "Format Yz code in canonical style",
+			Request: api.FormatRequest{},
+			Responses: withErrors([]openapi.Response{{Status: http.StatusOK, Body: api.FormatResponse{}}},
+				slices.Concat(keyedErrors, codeErrors)...),
+			Secured: true,
+		}},
+		{http.MethodPost, "/parse", true, s.parse, openapi.Operation{
+			Summary: "Tokenize and parse Yz code into a syntax tree",
+			Request: api.ParseRequest{},
+			Responses: withErrors([]openapi.Response{{Status: http.StatusOK, Body: api.ParseResponse{}}},
+				slices.Concat(keyedErrors, codeErrors)...),
+			Secured: true,
+		}},
+		{http.MethodPost, "/lint", true, s.lint, openapi.Operation{
+			Summary: "Check Yz code for suspicious constructs",
+			Description: "Reports unused bindings, shadowed declarations, unreachable code and naming problems, " +
+				"each tagged with its rule. Rules are enabled unless the request's rules map turns them off.",
+			Request: api.LintRequest{},
+			Responses: withErrors([]openapi.Response{{Status: http.StatusOK, Body: api.LintResponse{}}},
+				slices.Concat(keyedErrors, codeErrors)...),
+			Secured: true,
+		}},
+		{http.MethodPost, "/project/import", true, s.importProject, openapi.Operation{
+			Summary: "Unpack a zip or tar.gz project archive",
+			Description: "Takes the archive as the request body and answers with its main.yz as code and its other " +
+				".yz files as files, ready for /execute. Other files are listed as skipped. If main.yz isn't at " +
+				"the root, the archive's only top-level directory is taken as the project.",
+			RequestContentType: "application/octet-stream",
+			RequestSchema:      map[string]any{"type": "string", "format": "binary"},
+			Responses: withErrors([]openapi.Response{{Status: http.StatusOK, Body: api.ImportResponse{}}},
+				slices.Concat(keyedErrors, codeErrors)...),
+			Secured: true,
+		}},
+		{http.MethodPost, "/project/export", true, s.exportProject, openapi.Operation{
+			Summary: "Pack a project into a zip or tar.gz archive",
+			Description: "Answers with an archive holding the code as main.yz and the files at their paths. " +
+				"Generated Go code and output, if sent, are added under .yzplay/.",
+			Request: api.ExportRequest{},
+			Responses: withErrors([]openapi.Response{{
+				Status:      http.StatusOK,
+				Description: "The archive, as application/zip or application/gzip",
+				ContentType: "application/octet-stream",
+				Schema:      map[string]any{"type": "string", "format": "binary"},
+			}}, slices.Concat(keyedErrors, codeErrors)...),
+			Secured: true,
+		}},
+		{http.MethodGet, "/lsp", true, s.languageServer, openapi.Operation{
+			Summary: "Connect to the Yz language server over a WebSocket",
+			Description: "Upgrades to a WebSocket carrying Language Server Protocol JSON-RPC messages, one per " +
+				"WebSocket text message. The server supports full document sync, diagnostics, hover, " +
+				"go-to-definition and completion. Browsers can pass the API key as the api_key query parameter.",
+			Responses: withErrors([]openapi.Response{{Status: http.StatusSwitchingProtocols, Description: "WebSocket established"}},
+				slices.Concat(keyedErrors, []int{http.StatusBadRequest, http.StatusForbidden})...),
+			Secured: true,
+		}},
+	}
+}
+
+// Error statuses shared by groups of routes
+var (
+	keyedErrors   = []int{http.StatusUnauthorized, http.StatusTooManyRequests}
+	codeErrors    = []int{http.StatusBadRequest, http.StatusRequestEntityTooLarge}
+	executeErrors = []int{http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusServiceUnavailable}
+)
+
+// withErrors adds error envelope responses for the given statuses
+func withErrors(responses []openapi.Response, statuses ...int) []openapi.Response {
+	for _, status := range statuses {
+		responses = append(responses, openapi.Response{Status: status, Body: api.ErrorResponse{}})
+	}
+	return responses
+}
+
+// register adds the API routes under the versioned and legacy prefixes, plus
+// the OpenAPI document. authenticate runs before every keyed route.
+func (s *apiServer) register(r *gin.Engine, authenticate gin.HandlerFunc) error {
+	routes := s.routes()
+	for _, prefix := range []string{api.BasePath, legacyAPIPrefix} {
+		group := r.Group(prefix)
+		if prefix == legacyAPIPrefix {
+			group.Use(deprecated, apierror.Legacy)
+		}
+		for _, rt := range routes {
+			if rt.keyed {
+				group.Handle(rt.method, rt.path, authenticate, rt.handler)
+			} else {
+				group.Handle(rt.method, rt.path, rt.handler)
+			}
+		}
+	}
+
+	spec, err := json.Marshal(openAPIDocument(routes))
+	if err != nil {
+		return err
+	}
+	r.GET(api.BasePath+"/openapi.json", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json", spec)
+	})
+	return nil
+}
+
+// openAPIDocument documents the routes, with schemas generated from the pkg/api types
+func openAPIDocument(routes []route) *openapi.Document {
+	doc := openapi.New("Yz Playground API", "1.0.0", api.BasePath, auth.HeaderName)
+	openapi.Enum(doc, api.ErrorCodes...)
+	for _, rt := range routes {
+		doc.Add(rt.method, rt.path, rt.doc)
+	}
+	doc.Add(http.MethodGet, "/openapi.json", openapi.Operation{
+		Summary: "Get this OpenAPI document",
+		Responses: []openapi.Response{{
+			Status: http.StatusOK,
+			Schema: map[string]any{"type": "object"},
+		}},
+	})
+	return doc
+}
+
+// deprecated points callers of the legacy paths to their versioned successors
+func deprecated(c *gin.Context) {
+	c.Header("Deprecation", "true")
+	if route := c.FullPath(); route != "" {
+		c.Header("Link", "<"+api.BasePath+strings.TrimPrefix(route, legacyAPIPrefix)+`>; rel="successor-version"`)
+	}
+	c.Next()
+}
+
+// liveness reports that the process is up and serving requests
+func (s *apiServer) liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, api.HealthResponse{
+		Status:  "healthy",
+		Service: serviceName,
+	})
+}
+
+// ready reports whether the sandbox pipeline can accept executions
+func (s *apiServer) ready(c *gin.Context) {
+	healthy, results := s.readiness.Run(c.Request.Context())
+
+	response := api.ReadinessResponse{
+		Status:  "ready",
+		Service: serviceName,
+		Checks:  make([]api.HealthCheck, 0, len(results)),
+	}
+	for _, result := range results {
+		response.Checks = append(response.Checks, api.HealthCheck{
+			Name:      result.Name,
+			Status:    result.Status,
+			LatencyMs: result.Latency.Milliseconds(),
+			Error:     result.Error,
+			CheckedAt: result.CheckedAt.UTC().Format(time.RFC3339),
+		})
+	}
+
+	status := http.StatusOK
+	if !healthy {
+		response.Status = "not_ready"
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, response)
+}
+
+// config reports the limits that apply to the caller
+func (s *apiServer) config(c *gin.Context) {
+	cfg := s.settings.Get()
+	key := auth.FromContext(c)
+	c.JSON(http.StatusOK, api.ConfigResponse{
+		MaxExecutionTime: key.ExecutionTimeLimit(cfg.MaxExecutionTime),
+		MaxMemory:        key.MemoryLimit(cfg.MaxMemory),
+		MaxCodeSize:      cfg.MaxCodeSize,
+		MaxOutputSize:    cfg.MaxOutputSize,
+		MaxProjectFiles:  cfg.MaxProjectFiles,
+		MaxPathDepth:     cfg.MaxPathDepth,
+		MaxArchiveSize:   cfg.MaxArchiveSize,
+		MaxBenchmarkRuns: cfg.MaxBenchmarkRuns,
+		Compilers:        slices.Sorted(maps.Keys(cfg.Compilers())),
+	})
+}
+
+// compilerVersion reports the version of the compiler named by the compiler
+// query parameter, or of the default compiler
+func (s *apiServer) compilerVersion(c *gin.Context) {
+	path, ok := s.compilerPath(c, c.Query("compiler"))
+	if !ok {
+		return
+	}
+	version, err := s.manager.CompilerVersion(c.Request.Context(), path)
+	if err != nil {
+		apierror.Abort(c, http.StatusServiceUnavailable, api.CodeSandboxUnavailable, "Failed to get compiler version")
+		return
+	}
+
+	c.JSON(http.StatusOK, api.VersionResponse{Version: version})
+}
+
+// execute compiles and runs code and answers with the result
+func (s *apiServer) execute(c *gin.Context) {
+	code, opts, ok := s.executeOptions(c)
+	if !ok {
+		return
+	}
+
+	// Execute code in sandbox
+	result, err := s.manager.ExecuteWithOptions(c.Request.Context(), code, opts)
+	if err != nil {
+		executeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, executeResponse(code, opts, result))
+}
+
+// executeStream compiles and runs code, sending program output as server-sent
+// events while it runs, followed by a single result or error event
+func (s *apiServer) executeStream(c *gin.Context) {
+	code, opts, ok := s.executeOptions(c)
+	if !ok {
+		return
+	}
+
+	stream := &eventStream{c: c}
+	opts.Output = stream
+	result, err := s.manager.ExecuteWithOptions(c.Request.Context(), code, opts)
+	if err != nil {
+		if !stream.started {
+			// Nothing has been sent yet, so report the failure with a status code
+			executeError(c, err)
+			return
+		}
+		c.SSEvent(api.EventError, api.ErrorResponse{Error: apierror.Body(c, api.CodeSandboxUnavailable, err.Error(), nil)})
+		return
+	}
+
+	c.SSEvent(api.EventResult, executeResponse(code, opts, result))
+}
+
+// compile builds code without running it
+func (s *apiServer) compile(c *gin.Context) {
+	var req api.CompileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidRequest(c, err)
+		return
+	}
+	opts, ok := s.limitOptions(c, req.Code, req.Files, req.Timeout, req.Memory)
+	if !ok {
+		return
+	}
+	if opts.CompilerPath, ok = s.compilerPath(c, req.Compiler); !ok {
+		return
+	}
+	opts.CompileOnly = true
+
+	result, err := s.manager.ExecuteWithOptions(c.Request.Context(), req.Code, opts)
+	if err != nil {
+		executeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, api.CompileResponse{
+		Success:     result.Success,
+		Output:      result.Output,
+		Error:       result.Error,
+		ErrorCode:   result.ErrorCode,
+		Truncated:   result.Truncated,
+		CompileTime: result.CompileTime,
+	})
+}
+
+// format rewrites code in canonical Yz style. Code that can't be parsed is
+// answered with the syntax error and its position.
+func (s *apiServer) format(c *gin.Context) {
+	var req api.FormatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidRequest(c, err)
+		return
+	}
+	if !s.checkCodeSize(c, req.Code) {
+		return
+	}
+
+	formatted, err := yz.Format(req.Code)
+	if err != nil {
+		var syntaxErr *yz.SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			apierror.Abort(c, http.StatusInternalServerError, api.CodeInternal, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, api.FormatResponse{
+			Code:      req.Code,
+			Error:     syntaxErr.Msg,
+			ErrorCode: api.CodeSyntaxError,
+			Position:  position(syntaxErr.Pos),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, api.FormatResponse{
+		Success: true,
+		Code:    formatted,
+		Changed: formatted != req.Code,
+	})
+}
+
+// parse answers the tokens and syntax tree of code. Code that can't be parsed
+// is answered with the tokens read, the syntax error and its position.
+func (s *apiServer) parse(c *gin.Context) {
+	var req api.ParseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidRequest(c, err)
+		return
+	}
+	if !s.checkCodeSize(c, req.Code) {
+		return
+	}
+
+	file, err := yz.Parse(req.Code)
+	resp := api.ParseResponse{Success: err == nil, Tokens: make([]api.SyntaxToken, 0, len(file.Tokens))}
+	for _, tok := range file.Tokens {
+		if tok.Kind != yz.EOF {
+			resp.Tokens = append(resp.Tokens, api.SyntaxToken{
+				Kind:     tok.Kind.String(),
+				Text:     tok.Text,
+				Position: *position(tok.Pos),
+				End:      *position(tok.End),
+			})
+		}
+	}
+	if err != nil {
+		var syntaxErr *yz.SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			apierror.Abort(c, http.StatusInternalServerError, api.CodeInternal, err.Error())
+			return
+		}
+		resp.Error = syntaxErr.Msg
+		resp.ErrorCode = api.CodeSyntaxError
+		resp.Position = position(syntaxErr.Pos)
+	} else {
+		resp.Tree = syntaxNode(file.Root)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// syntaxNode converts a syntax tree to its API form
+func syntaxNode(n *yz.Node) *api.SyntaxNode {
+	node := &api.SyntaxNode{Kind: n.Kind.String(), Text: n.Text, Position: *position(n.Span.Start), End: *position(n.Span.End)}
+	for _, child := range n.Children {
+		node.Children = append(node.Children, syntaxNode(child))
+	}
+	return node
+}
+
+// lint checks code with the requested rules. Code that can't be parsed is
+// answered with the syntax error and its position.
+func (s *apiServer) lint(c *gin.Context) {
+	var req api.LintRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidRequest(c, err)
+		return
+	}
+	for rule := range req.Rules {
+		if !slices.Contains(lint.Rules, rule) {
+			apierror.AbortWithDetails(c, http.StatusBadRequest, api.CodeInvalidRequest, "Unknown lint rule",
+				map[string]any{"rule": rule, "rules": lint.Rules})
+			return
+		}
+	}
+	if !s.checkCodeSize(c, req.Code) {
+		return
+	}
+
+	warnings, err := lint.Lint(req.Code, lint.Options{Rules: req.Rules})
+	if err != nil {
+		var syntaxErr *yz.SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			apierror.Abort(c, http.StatusInternalServerError, api.CodeInternal, err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, api.LintResponse{
+			Warnings:  []api.LintWarning{},
+			Error:     syntaxErr.Msg,
+			ErrorCode: api.CodeSyntaxError,
+			Position:  position(syntaxErr.Pos),
+		})
+		return
+	}
+
+	resp := api.LintResponse{Success: true, Warnings: make([]api.LintWarning, 0, len(warnings))}
+	for _, w := range warnings {
+		warning := api.LintWarning{Rule: w.Rule, Message: w.Message, Position: *position(w.Pos), End: *position(w.End)}
+		if w.Fix != nil {
+			warning.Fix = &api.LintFix{Description: w.Fix.Description}
+			for _, edit := range w.Fix.Edits {
+				warning.Fix.Edits = append(warning.Fix.Edits, api.TextEdit{
+					Position: *position(edit.Pos),
+					End:      *position(edit.End),
+					NewText:  edit.NewText,
+				})
+			}
+		}
+		resp.Warnings = append(resp.Warnings, warning)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// position converts a source position to its API form
+func position(pos yz.Pos) *api.Position {
+	return &api.Position{Line: pos.Line, Column: pos.Column, Offset: pos.Offset}
+}
+
+// executeOptions binds an execution request and applies the caller's limits to it.
+// It writes an error response and returns false if the request is invalid.
+func (s *apiServer) executeOptions(c *gin.Context) (string, sandbox.ExecutionOptions, bool) {
+	var req api.ExecuteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidRequest(c, err)
+		return "", sandbox.ExecutionOptions{}, false
+	}
+
+	if req.ShowGeneratedCode && len(req.Files) > 0 {
+		apierror.Abort(c, http.StatusBadRequest, api.CodeInvalidRequest, "show_generated_code can't be used with files")
+		return "", sandbox.ExecutionOptions{}, false
+	}
+	if req.ShowGeneratedCode && req.Profile {
+		apierror.Abort(c, http.StatusBadRequest, api.CodeInvalidRequest, "show_generated_code can't be used with profile")
+		return "", sandbox.ExecutionOptions{}, false
+	}
+	opts, ok := s.limitOptions(c, req.Code, req.Files, req.Timeout, req.Memory)
+	if !ok {
+		return "", sandbox.ExecutionOptions{}, false
+	}
+	if opts.CompilerPath, ok = s.compilerPath(c, req.Compiler); !ok {
+		return "", sandbox.ExecutionOptions{}, false
+	}
+	opts.ShowGeneratedCode = req.ShowGeneratedCode
+	opts.Profile = req.Profile
+	return req.Code, opts, true
+}
+
+// compilerPath returns the path of the compiler version a request names, or
+// "" for the default compiler. It writes an error response and returns false
+// if no compiler version has that name.
+func (s *apiServer) compilerPath(c *gin.Context, name string) (string, bool) {
+	if name == "" {
+		return "", true
+	}
+	path, ok := s.settings.Get().Compilers()[name]
+	if !ok {
+		apierror.Abort(c, http.StatusBadRequest, api.CodeInvalidRequest, fmt.Sprintf("unknown compiler %q", name))
+	}
+	return path, ok
+}
+
+// limitOptions checks the project files and code size and applies the
+// caller's limits to the requested ones; a request may ask for less but never
+// more. It writes an error response and returns false if the files are
+// invalid or the code is too large.
+func (s *apiServer) limitOptions(c *gin.Context, code string, files map[string]string, timeout, memory int) (sandbox.ExecutionOptions, bool) {
+	if !s.checkProjectFiles(c, files) || !s.checkSize(c, projectSize(code, files)) {
+		return sandbox.ExecutionOptions{}, false
+	}
+
+	opts := s.callerLimits(c, timeout, memory)
+	opts.Files = files
+	return opts, true
+}
+
+// callerLimits applies the caller's limits to the requested timeout in
+// milliseconds and memory in MB; zero asks for the most allowed
+func (s *apiServer) callerLimits(c *gin.Context, timeout, memory int) sandbox.ExecutionOptions {
+	cfg := s.settings.Get()
+	key := auth.FromContext(c)
+	maxExecutionTime := key.ExecutionTimeLimit(cfg.MaxExecutionTime)
+	if timeout > 0 && timeout < maxExecutionTime {
+		maxExecutionTime = timeout
+	}
+	maxMemory := key.MemoryLimit(cfg.MaxMemory)
+	if memory > 0 && memory < maxMemory {
+		maxMemory = memory
+	}
+
+	return sandbox.ExecutionOptions{
+		Timeout:       time.Duration(maxExecutionTime) * time.Millisecond,
+		MaxMemory:     int64(maxMemory) * 1024 * 1024, // Convert MB to bytes
+		MaxOutputSize: cfg.MaxOutputSize,
+	}
+}
+
+// checkCodeSize writes an error response and returns false if code is larger than allowed
+func (s *apiServer) checkCodeSize(c *gin.Context, code string) bool {
+	return s.checkSize(c, len(code))
+}
+
+// checkSize writes an error response and returns false if size bytes of
+// code, counting every project file, are more than allowed
+func (s *apiServer) checkSize(c *gin.Context, size int) bool {
+	limit := s.settings.Get().MaxCodeSize
+	if size > limit {
+		apierror.AbortWithDetails(c, http.StatusRequestEntityTooLarge, api.CodeTooLarge, "Code size exceeds maximum limit",
+			map[string]any{"size": size, "limit": limit})
+		return false
+	}
+	return true
+}
+
+// invalidRequest writes the response for a request body that couldn't be bound
+func invalidRequest(c *gin.Context, err error) {
+	apierror.AbortWithDetails(c, http.StatusBadRequest, api.CodeInvalidRequest, "Invalid request body",
+		map[string]any{"reason": err.Error()})
+}
+
+// executeError writes the response for an execution the sandbox couldn't carry out
+func executeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, sandbox.ErrQueueFull):
+		c.Header("Retry-After", "1")
+		apierror.Abort(c, http.StatusServiceUnavailable, api.CodeQueueFull, "Too many executions are waiting, try again shortly")
+	case errors.Is(err, sandbox.ErrShuttingDown):
+		apierror.Abort(c, http.StatusServiceUnavailable, api.CodeSandboxUnavailable, "Server is shutting down")
+	default:
+		apierror.Abort(c, http.StatusServiceUnavailable, api.CodeSandboxUnavailable, err.Error())
+	}
+}
+
+// executeResponse converts the sandbox result of running code with opts to its API form
+func executeResponse(code string, opts sandbox.ExecutionOptions, result *sandbox.ExecutionResult) api.ExecuteResponse {
+	return api.ExecuteResponse{
+		Success:       result.Success,
+		Output:        result.Output,
+		GeneratedCode: result.GeneratedCode,
+		Error:         result.Error,
+		ErrorCode:     result.ErrorCode,
+		Truncated:     result.Truncated,
+		ExecutionTime: result.ExecutionTime,
+		MemoryUsed:    int(result.MemoryUsed / 1024 / 1024), // Convert bytes to MB
+		Profile:       profileResponse(code, opts.Files, result.Profile),
+	}
+}
+
+// eventStream sends program output to the client as server-sent events
+type eventStream struct {
+	c       *gin.Context
+	started bool // whether any event has been written
+}
+
+// Write implements io.Writer
+func (s *eventStream) Write(p []byte) (int, error) {
+	s.started = true
+	s.c.SSEvent(api.EventOutput, api.OutputEvent{Output: string(p)})
+	s.c.Writer.Flush()
+	return len(p), nil
+}
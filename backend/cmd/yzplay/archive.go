@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+
+	"yz-playground/internal/apierror"
+	"yz-playground/internal/archive"
+	"yz-playground/internal/sandbox"
+	"yz-playground/pkg/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+// archiveContentTypes maps archive formats to their media types
+var archiveContentTypes = map[string]string{
+	archive.FormatZip:   "application/zip",
+	archive.FormatTarGz: "application/gzip",
+}
+
+// importProject unpacks a zip or tar.gz archive sent as the request body into
+// the code and files of an execution request
+func (s *apiServer) importProject(c *gin.Context) {
+	cfg := s.settings.Get()
+	data, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, int64(cfg.MaxArchiveSize)))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			apierror.AbortWithDetails(c, http.StatusRequestEntityTooLarge, api.CodeTooLarge, "Archive size exceeds maximum limit",
+				map[string]any{"limit": cfg.MaxArchiveSize})
+			return
+		}
+		invalidRequest(c, err)
+		return
+	}
+
+	project, skipped, err := archive.Read(data, cfg.MaxCodeSize)
+	if err != nil {
+		if errors.Is(err, archive.ErrTooLarge) {
+			apierror.AbortWithDetails(c, http.StatusRequestEntityTooLarge, api.CodeTooLarge, "Code size exceeds maximum limit",
+				map[string]any{"limit": cfg.MaxCodeSize})
+			return
+		}
+		apierror.AbortWithDetails(c, http.StatusBadRequest, api.CodeInvalidRequest, "Invalid project archive",
+			map[string]any{"reason": err.Error()})
+		return
+	}
+	if !s.checkProjectFiles(c, project.Files) {
+		return
+	}
+
+	if skipped == nil {
+		skipped = []string{}
+	}
+	c.JSON(http.StatusOK, api.ImportResponse{Code: project.Code, Files: project.Files, Skipped: skipped})
+}
+
+// exportProject packs the request's code and files, with the generated code
+// and output it carries, into an archive download
+func (s *apiServer) exportProject(c *gin.Context) {
+	var req api.ExportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidRequest(c, err)
+		return
+	}
+	if req.Format == "" {
+		req.Format = archive.FormatZip
+	}
+	if !slices.Contains(archive.Formats, req.Format) {
+		apierror.AbortWithDetails(c, http.StatusBadRequest, api.CodeInvalidRequest, "Unknown archive format",
+			map[string]any{"format": req.Format, "formats": archive.Formats})
+		return
+	}
+	if !s.checkProjectFiles(c, req.Files) || !s.checkSize(c, projectSize(req.Code, req.Files)) {
+		return
+	}
+
+	var buf bytes.Buffer
+	err := archive.Write(&buf, req.Format, archive.Project{
+		Code:          req.Code,
+		Files:         req.Files,
+		GeneratedCode: req.GeneratedCode,
+		Output:        req.Output,
+	})
+	if err != nil {
+		apierror.Abort(c, http.StatusInternalServerError, api.CodeInternal, err.Error())
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="yz-project.`+req.Format+`"`)
+	c.Data(http.StatusOK, archiveContentTypes[req.Format], buf.Bytes())
+}
+
+// checkProjectFiles writes an error response and returns false if files
+// aren't valid project files
+func (s *apiServer) checkProjectFiles(c *gin.Context, files map[string]string) bool {
+	cfg := s.settings.Get()
+	if err := sandbox.CheckProjectFiles(files, cfg.MaxProjectFiles, cfg.MaxPathDepth); err != nil {
+		apierror.AbortWithDetails(c, http.StatusBadRequest, api.CodeInvalidRequest, "Invalid project files",
+			map[string]any{"reason": err.Error()})
+		return false
+	}
+	return true
+}
+
+// projectSize returns the bytes of code and files together
+func projectSize(code string, files map[string]string) int {
+	size := len(code)
+	for _, content := range files {
+		size += len(content)
+	}
+	return size
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"slices"
+	"time"
+
+	"yz-playground/internal/apierror"
+	"yz-playground/pkg/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultBenchmarkRuns is how many runs a benchmark makes unless it asks otherwise
+const defaultBenchmarkRuns = 10
+
+// benchmark builds code once, runs it several times and answers with
+// statistics of the runs
+func (s *apiServer) benchmark(c *gin.Context) {
+	var req api.BenchmarkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidRequest(c, err)
+		return
+	}
+	maxRuns := s.settings.Get().MaxBenchmarkRuns
+	if req.Runs == 0 {
+		req.Runs = min(defaultBenchmarkRuns, maxRuns)
+	}
+	if req.Runs < 1 || req.Runs > maxRuns {
+		apierror.AbortWithDetails(c, http.StatusBadRequest, api.CodeInvalidRequest, "Invalid number of runs",
+			map[string]any{"runs": req.Runs, "limit": maxRuns})
+		return
+	}
+	opts, ok := s.limitOptions(c, req.Code, req.Files, req.Timeout, req.Memory)
+	if !ok {
+		return
+	}
+	opts.Runs = req.Runs
+
+	result, err := s.manager.ExecuteWithOptions(c.Request.Context(), req.Code, opts)
+	if err != nil {
+		executeError(c, err)
+		return
+	}
+
+	wall := make([]float64, len(result.Runs))
+	cpu := make([]float64, len(result.Runs))
+	memory := make([]float64, len(result.Runs))
+	for i, run := range result.Runs {
+		wall[i] = milliseconds(run.WallTime)
+		cpu[i] = milliseconds(run.CPUTime)
+		memory[i] = float64(run.MemoryUsed)
+	}
+	c.JSON(http.StatusOK, api.BenchmarkResponse{
+		Success:     result.Success,
+		Output:      result.Output,
+		Error:       result.Error,
+		ErrorCode:   result.ErrorCode,
+		Runs:        len(result.Runs),
+		CompileTime: result.CompileTime,
+		WallTime:    benchmarkStats(wall),
+		CPUTime:     benchmarkStats(cpu),
+		PeakMemory:  benchmarkStats(memory),
+	})
+}
+
+// benchmarkStats returns the minimum, median and 95th percentile of values,
+// using the nearest rank for the percentile
+func benchmarkStats(values []float64) api.BenchmarkStats {
+	if len(values) == 0 {
+		return api.BenchmarkStats{}
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+
+	n := len(sorted)
+	median := sorted[n/2]
+	if n%2 == 0 {
+		median = (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+	return api.BenchmarkStats{
+		Min:    sorted[0],
+		Median: median,
+		P95:    sorted[int(math.Ceil(0.95*float64(n)))-1],
+	}
+}
+
+// milliseconds converts d to fractional milliseconds, to the microsecond
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
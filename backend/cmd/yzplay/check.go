@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"yz-playground/internal/config"
+	"yz-playground/internal/health"
+)
+
+// runCheck runs the readiness checks once and prints their results
+func runCheck(args []string) int {
+	flags := flag.NewFlagSet("yzplay check", flag.ContinueOnError)
+	cfg, err := config.LoadFlags(flags, args)
+	if err != nil {
+		return configError(err)
+	}
+	if flags.NArg() > 0 {
+		return usageError(flags, "check takes no arguments")
+	}
+
+	newLogger(cfg, os.Stderr)
+
+	manager := newSandboxManager(cfg)
+	defer manager.Cleanup()
+
+	checker := health.New()
+	registerReadinessChecks(checker, manager)
+	healthy, results := checker.Run(context.Background())
+
+	for _, result := range results {
+		line := fmt.Sprintf("%-4s  %-22s %6dms", result.Status, result.Name, result.Latency.Milliseconds())
+		if result.Error != "" {
+			line += "  " + result.Error
+		}
+		fmt.Println(line)
+	}
+
+	if !healthy {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"yz-playground/internal/apierror"
+	"yz-playground/internal/exercise"
+	"yz-playground/internal/sandbox"
+	"yz-playground/pkg/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxCaseOutput bounds the output kept of each case of a submission
+const maxCaseOutput = 64 << 10
+
+// listExercises answers with every exercise's ID and title
+func (s *apiServer) listExercises(c *gin.Context) {
+	exercises := s.exercises.List()
+	resp := api.ExerciseListResponse{Exercises: make([]api.ExerciseSummary, 0, len(exercises))}
+	for _, e := range exercises {
+		resp.Exercises = append(resp.Exercises, api.ExerciseSummary{ID: e.ID, Title: e.Title})
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// getExercise answers with an exercise, leaving out its hidden cases
+func (s *apiServer) getExercise(c *gin.Context) {
+	e, ok := s.exercise(c)
+	if !ok {
+		return
+	}
+	resp := api.ExerciseResponse{
+		ID:          e.ID,
+		Title:       e.Title,
+		Prompt:      e.Prompt,
+		StarterCode: e.StarterCode,
+		TimeLimit:   e.TimeLimit,
+		MemoryLimit: e.MemoryLimit,
+		Cases:       len(e.Cases),
+		Samples:     []api.ExerciseSample{},
+	}
+	for _, sample := range e.Samples() {
+		resp.Samples = append(resp.Samples, api.ExerciseSample{Stdin: sample.Stdin, Expected: sample.Expected})
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// submitExercise builds a solution once, runs it on every case of the
+// exercise and answers with a verdict per case
+func (s *apiServer) submitExercise(c *gin.Context) {
+	e, ok := s.exercise(c)
+	if !ok {
+		return
+	}
+	var req api.SubmitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidRequest(c, err)
+		return
+	}
+
+	// The exercise's limits apply to each case, within the caller's limits.
+	// The build and every case share the time one execution may take, so
+	// cases it leaves no time for are judged as over the time limit.
+	opts, ok := s.limitOptions(c, req.Code, req.Files, e.TimeLimit, e.MemoryLimit)
+	if !ok {
+		return
+	}
+	opts.CaseTimeout = opts.Timeout
+	opts.Timeout = s.callerLimits(c, 0, 0).Timeout
+	opts.MaxOutputSize = min(opts.MaxOutputSize, maxCaseOutput)
+	for _, tc := range e.Cases {
+		opts.Cases = append(opts.Cases, tc.Stdin)
+	}
+
+	result, err := s.manager.ExecuteWithOptions(c.Request.Context(), req.Code, opts)
+	if err != nil {
+		executeError(c, err)
+		return
+	}
+
+	resp := api.SubmitResponse{
+		Success:     true,
+		Verdict:     api.VerdictAccepted,
+		Cases:       make([]api.CaseVerdict, 0, len(e.Cases)),
+		Total:       len(e.Cases),
+		CompileTime: result.CompileTime,
+	}
+	if !result.Success {
+		resp.ErrorCode = result.ErrorCode
+		if result.Outcome == sandbox.OutcomeCompileError {
+			resp.Error = result.Error
+		}
+	}
+	for i, tc := range e.Cases {
+		v := api.CaseVerdict{Case: i + 1, Sample: tc.Sample}
+		if i < len(result.Cases) {
+			run := result.Cases[i]
+			v.Verdict, v.Message = caseVerdict(run, tc, opts.MaxOutputSize)
+			v.TimeMs = milliseconds(run.WallTime)
+			v.MemoryUsed = run.MemoryUsed
+			if tc.Sample {
+				v.Output = run.Output
+			}
+		} else {
+			v.Verdict, v.Message = unrunVerdict(result)
+		}
+
+		if v.Verdict == api.VerdictAccepted {
+			resp.Passed++
+		} else if resp.Success {
+			resp.Success = false
+			resp.Verdict = v.Verdict
+		}
+		resp.Cases = append(resp.Cases, v)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// exercise returns the exercise named in the request's path. It writes an
+// error response and returns false if there is no such exercise.
+func (s *apiServer) exercise(c *gin.Context) (*exercise.Exercise, bool) {
+	id := c.Param("id")
+	e := s.exercises.Get(id)
+	if e == nil {
+		apierror.AbortWithDetails(c, http.StatusNotFound, api.CodeNotFound, "No such exercise",
+			map[string]any{"id": id})
+		return nil, false
+	}
+	return e, true
+}
+
+// caseVerdict judges the run of a case. The message says what went wrong
+// without revealing the case.
+func caseVerdict(run sandbox.CaseRun, tc exercise.Case, maxOutput int) (string, string) {
+	switch run.Outcome {
+	case sandbox.OutcomeTimeout:
+		return api.VerdictTimeLimit, ""
+	case sandbox.OutcomeOOM:
+		return api.VerdictMemoryLimit, ""
+	case sandbox.OutcomeOutputLimit:
+		return api.VerdictWrongAnswer, fmt.Sprintf("output exceeded %d bytes", maxOutput)
+	case sandbox.OutcomeRuntimeError:
+		return api.VerdictRuntimeError, fmt.Sprintf("exit status %d", run.ExitStatus)
+	}
+	if !exercise.Matches(run.Output, tc.Expected) {
+		return api.VerdictWrongAnswer, ""
+	}
+	return api.VerdictAccepted, ""
+}
+
+// unrunVerdict judges a case the program never ran on, because the build
+// failed or the submission as a whole was stopped
+func unrunVerdict(result *sandbox.ExecutionResult) (string, string) {
+	switch result.Outcome {
+	case sandbox.OutcomeCompileError:
+		return api.VerdictCompileError, ""
+	case sandbox.OutcomeTimeout:
+		return api.VerdictTimeLimit, "the submission timed out before this case ran"
+	case sandbox.OutcomeOOM:
+		return api.VerdictMemoryLimit, "the submission ran out of memory before this case ran"
+	}
+	return api.VerdictRuntimeError, "the submission stopped before this case ran"
+}
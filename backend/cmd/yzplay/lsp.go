@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"yz-playground/internal/apierror"
+	"yz-playground/internal/auth"
+	"yz-playground/internal/logger"
+	"yz-playground/internal/lsp"
+	"yz-playground/pkg/api"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// lspMessageOverhead is how much larger than the code a language server message may be
+const lspMessageOverhead = 64 * 1024
+
+// lspWriteTimeout bounds each message written to a language server client
+const lspWriteTimeout = 10 * time.Second
+
+// languageServer upgrades the request to a WebSocket and runs a language
+// server session over it, with one JSON-RPC message per WebSocket message.
+// Documents are checked with compile-only runs in the sandbox, each counted
+// against the caller's API key rate limit like a request.
+func (s *apiServer) languageServer(c *gin.Context) {
+	if !websocket.IsWebSocketUpgrade(c.Request) {
+		apierror.Abort(c, http.StatusBadRequest, api.CodeInvalidRequest, "Expected a WebSocket upgrade")
+		return
+	}
+	// Browsers don't apply CORS to WebSockets, so check the origin here
+	origin := c.GetHeader("Origin")
+	if origins := s.settings.Get().Origins(); origin != "" && !slices.Contains(origins, "*") && !slices.Contains(origins, origin) {
+		apierror.Abort(c, http.StatusForbidden, api.CodeInvalidRequest, "Origin not allowed")
+		return
+	}
+
+	upgrader := websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+	ws, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already answered the request
+		return
+	}
+
+	cfg := s.settings.Get()
+	ws.SetReadLimit(int64(cfg.MaxCodeSize) + lspMessageOverhead)
+	key := auth.FromContext(c)
+	opts := s.callerLimits(c, 0, 0)
+	opts.CompileOnly = true
+
+	ctx := c.Request.Context()
+	log := logger.FromContext(ctx)
+	log.Info("Language server session started")
+	err = lsp.Serve(ctx, &wsConn{ws: ws}, lsp.Options{
+		Compile: func(ctx context.Context, code string) (string, bool, error) {
+			if ok, _ := s.keys.Allow(key); !ok {
+				return "", false, lsp.ErrRateLimited
+			}
+			result, err := s.manager.ExecuteWithOptions(ctx, code, opts)
+			if err != nil {
+				return "", false, err
+			}
+			if !result.Success && result.ErrorCode != api.CodeCompileError {
+				return "", false, errors.New(result.Error)
+			}
+			// Drop the line the sandbox puts before the compiler's output
+			output := result.Output
+			if !result.Success {
+				_, output, _ = strings.Cut(result.Error, "\n")
+			}
+			return output, result.Success, nil
+		},
+		MaxDocumentSize: cfg.MaxCodeSize,
+		Version:         version,
+	})
+	if err != nil && !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+		log.Warn("Language server session failed", "error", err)
+	}
+	log.Info("Language server session ended")
+}
+
+// wsConn carries language server messages over a WebSocket
+type wsConn struct {
+	ws *websocket.Conn
+}
+
+// ReadMessage implements lsp.Conn, skipping binary messages
+func (c *wsConn) ReadMessage() ([]byte, error) {
+	for {
+		kind, data, err := c.ws.ReadMessage()
+		if err != nil {
+			return nil, err
+		}
+		if kind == websocket.TextMessage {
+			return data, nil
+		}
+	}
+}
+
+// WriteMessage implements lsp.Conn
+func (c *wsConn) WriteMessage(data []byte) error {
+	if err := c.ws.SetWriteDeadline(time.Now().Add(lspWriteTimeout)); err != nil {
+		return err
+	}
+	return c.ws.WriteMessage(websocket.TextMessage, data)
+}
+
+// Close implements lsp.Conn
+func (c *wsConn) Close() error {
+	return c.ws.Close()
+}
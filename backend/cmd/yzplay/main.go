@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"yz-playground/internal/config"
+	"yz-playground/internal/logger"
+	"yz-playground/internal/sandbox"
+)
+
+// commands maps subcommand names to their entry points
+var commands = map[string]func(args []string) int{
+	"serve":   runServe,
+	"run":     runRun,
+	"check":   runCheck,
+	"version": runVersion,
+}
+
+const usage = `yzplay is the Yz Playground backend.
+
+Usage:
+  yzplay <command> [flags] [arguments]
+
+Commands:
+  serve             start the HTTP API server
+  run <file.yz>     compile and run a Yz file in the sandbox and print the result
+  check             verify Docker, the sandbox container and the compiler
+  version           print version information
+
+Run "yzplay <command> -h" for the flags of a command.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	name := os.Args[1]
+	if name == "-h" || name == "-help" || name == "--help" || name == "help" {
+		fmt.Print(usage)
+		return
+	}
+
+	command, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "yzplay: unknown command %q\n\n%s", name, usage)
+		os.Exit(2)
+	}
+	os.Exit(command(os.Args[2:]))
+}
+
+// configError reports a configuration or flag error and returns the exit code
+func configError(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+	return 2
+}
+
+// usageError reports a misused command and returns the exit code
+func usageError(flags *flag.FlagSet, msg string) int {
+	fmt.Fprintf(os.Stderr, "%s: %s\n", flags.Name(), msg)
+	flags.Usage()
+	return 2
+}
+
+// newLogger creates the process logger from the configuration and makes it the default
+func newLogger(cfg *config.Config, output io.Writer) *logger.Logger {
+	log := logger.New(logger.Options{Level: cfg.LogLevel, Format: cfg.LogFormat, Output: output})
+	logger.SetDefault(log)
+	return log
+}
+
+// newSandboxManager creates a sandbox manager from the configuration
+func newSandboxManager(cfg *config.Config) *sandbox.Manager {
+	return sandbox.NewManager(&sandbox.SandboxConfig{
+		ContainerName:           cfg.SandboxContainer,
+		Image:                   cfg.SandboxImage,
+		MaxMemory:               int64(cfg.MaxMemory) * 1024 * 1024, // Convert MB to bytes
+		MaxExecutionTime:        cfg.MaxExecutionTime / 1000,        // Convert ms to seconds
+		MaxOutputSize:           cfg.MaxOutputSize,
+		WorkingDir:              cfg.SandboxWorkDir,
+		CompilerPath:            cfg.YZCompilerPath,
+		MaxConcurrentExecutions: cfg.MaxConcurrentExecutions,
+		MaxQueuedExecutions:     cfg.MaxQueuedExecutions,
+	})
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"math"
+	"strings"
+
+	"yz-playground/internal/profiling"
+	"yz-playground/internal/sandbox"
+	"yz-playground/pkg/api"
+)
+
+// profileTop is how many functions a profile table lists
+const profileTop = 20
+
+// profileResponse converts the profiles of a run of code and files to their
+// API form, or returns nil if the program never ran. The program wrote the
+// profiles, so only those that parse as profiles are passed on raw.
+func profileResponse(code string, files map[string]string, p *sandbox.Profile) *api.Profile {
+	if p == nil {
+		return nil
+	}
+	resp := &api.Profile{}
+	if p.CPU == nil && p.Heap == nil {
+		resp.Error = "the program ended before writing its profiles; they are written when its main returns"
+		return resp
+	}
+
+	sources := []string{code}
+	for _, content := range files {
+		sources = append(sources, content)
+	}
+	names := profiling.NewNames(sources...)
+
+	var problems []string
+	table := func(data []byte, sampleType string) (*api.ProfileTable, []byte) {
+		if data == nil {
+			return nil, nil
+		}
+		parsed, err := profiling.Parse(data)
+		if err != nil {
+			problems = append(problems, err.Error())
+			return nil, nil
+		}
+		t, err := profiling.Top(parsed, sampleType, profileTop, names)
+		if err != nil {
+			problems = append(problems, err.Error())
+			return nil, nil
+		}
+		return profileTable(t), data
+	}
+	resp.CPU, resp.CPUPprof = table(p.CPU, profiling.SampleCPU)
+	resp.Heap, resp.HeapPprof = table(p.Heap, profiling.SampleAllocSpace)
+	resp.Error = strings.Join(problems, "; ")
+	return resp
+}
+
+// profileTable converts a profile table to its API form
+func profileTable(t *profiling.Table) *api.ProfileTable {
+	resp := &api.ProfileTable{
+		SampleType: t.SampleType,
+		Unit:       t.Unit,
+		Total:      t.Total,
+		Functions:  make([]api.ProfileFunction, 0, len(t.Functions)),
+	}
+	for _, f := range t.Functions {
+		resp.Functions = append(resp.Functions, api.ProfileFunction{
+			Name:        f.Name,
+			GoName:      f.GoName,
+			Yz:          f.Yz,
+			File:        f.File,
+			Line:        f.Line,
+			Flat:        f.Flat,
+			FlatPercent: percent(f.Flat, t.Total),
+			Cum:         f.Cum,
+			CumPercent:  percent(f.Cum, t.Total),
+		})
+	}
+	return resp
+}
+
+// percent returns part as a percentage of total, to two decimals
+func percent(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(part)/float64(total)*10000) / 100
+}
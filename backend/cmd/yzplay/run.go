@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"yz-playground/internal/config"
+	"yz-playground/internal/sandbox"
+)
+
+// runRun executes a local Yz file through the same sandbox pipeline the server uses
+func runRun(args []string) int {
+	flags := flag.NewFlagSet("yzplay run", flag.ContinueOnError)
+	showGeneratedCode := flags.Bool("show-generated-code", false, "print the Go code generated by yzc")
+	cfg, err := config.LoadFlags(flags, args)
+	if err != nil {
+		return configError(err)
+	}
+	if flags.NArg() != 1 {
+		return usageError(flags, "expected exactly one .yz file")
+	}
+
+	// Logs go to stderr so stdout carries only the program's output
+	newLogger(cfg, os.Stderr)
+
+	path := flags.Arg(0)
+	code, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "yzplay run: %v\n", err)
+		return 1
+	}
+	if len(code) > cfg.MaxCodeSize {
+		fmt.Fprintf(os.Stderr, "yzplay run: %s is %d bytes, over the %d byte limit\n", path, len(code), cfg.MaxCodeSize)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	manager := newSandboxManager(cfg)
+	defer manager.Cleanup()
+
+	result, err := manager.ExecuteWithOptions(ctx, string(code), sandbox.ExecutionOptions{
+		Timeout:           time.Duration(cfg.MaxExecutionTime) * time.Millisecond,
+		MaxMemory:         int64(cfg.MaxMemory) * 1024 * 1024, // Convert MB to bytes
+		MaxOutputSize:     cfg.MaxOutputSize,
+		ShowGeneratedCode: *showGeneratedCode,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "yzplay run: %v\n", err)
+		return 1
+	}
+
+	if *showGeneratedCode && result.GeneratedCode != "" {
+		fmt.Println("=== Generated Go Code ===")
+		fmt.Println(result.GeneratedCode)
+		fmt.Println("=== End Generated Code ===")
+	}
+	if result.Output != "" {
+		fmt.Println(result.Output)
+	}
+	if !result.Success {
+		fmt.Fprintln(os.Stderr, result.Error)
+	}
+	fmt.Fprintf(os.Stderr, "%s: compile %dms, run %dms, total %dms\n",
+		result.Outcome, result.CompileTime, result.RunTime, result.ExecutionTime)
+
+	if !result.Success {
+		return 1
+	}
+	return 0
+}
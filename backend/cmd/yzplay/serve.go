@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	"yz-playground/internal/apierror"
+	"yz-playground/internal/auth"
+	"yz-playground/internal/config"
+	"yz-playground/internal/exercise"
+	"yz-playground/internal/health"
+	"yz-playground/internal/logger"
+	"yz-playground/internal/metrics"
+	"yz-playground/internal/sandbox"
+	"yz-playground/internal/tracing"
+	"yz-playground/pkg/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+// runServe starts the HTTP API server and blocks until it shuts down
+func runServe(args []string) int {
+	// Load configuration
+	flags := flag.NewFlagSet("yzplay serve", flag.ContinueOnError)
+	cfg, err := config.LoadFlags(flags, args)
+	if err != nil {
+		return configError(err)
+	}
+	if flags.NArg() > 0 {
+		return usageError(flags, "serve takes no arguments")
+	}
+
+	// Initialize structured logging
+	log := newLogger(cfg, os.Stdout)
+	if cfg.File != "" {
+		log.Info("Loaded configuration file", "file", cfg.File)
+	}
+
+	// Initialize tracing
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TraceExporter, "yz-playground-backend")
+	if err != nil {
+		log.Fatal("Failed to set up tracing", "error", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Initialize sandbox manager, starting the sandbox container if needed. The
+	// server still starts without it; readiness reports the container missing.
+	sandboxManager := newSandboxManager(cfg)
+	containerCtx, cancelContainer := context.WithTimeout(context.Background(), 30*time.Second)
+	if err := sandboxManager.EnsureContainer(containerCtx); err != nil {
+		log.Warn("Failed to start sandbox container", "error", err)
+	}
+	cancelContainer()
+
+	// Initialize metrics
+	appMetrics := metrics.New()
+	appMetrics.RegisterSandboxGauges(sandboxManager)
+	sandboxManager.SetObserver(appMetrics)
+
+	// Load API keys if configured
+	keyStore, err := auth.NewStore(nil)
+	if err != nil {
+		log.Fatal("Failed to create API key store", "error", err)
+	}
+	if cfg.APIKeysFile != "" {
+		if err := keyStore.Reload(cfg.APIKeysFile); err != nil {
+			log.Fatal("Failed to load API keys", "error", err)
+		}
+		log.Info("Loaded API keys", "count", keyStore.Len(), "file", cfg.APIKeysFile)
+	}
+
+	// Load exercises if configured
+	exercises := exercise.NewStore()
+	if cfg.ExercisesDir != "" {
+		if err := exercises.Reload(cfg.ExercisesDir); err != nil {
+			log.Fatal("Failed to load exercises", "error", err)
+		}
+		log.Info("Loaded exercises", "count", exercises.Len(), "dir", cfg.ExercisesDir)
+	}
+
+	// Limits and policy are read through the holder so they can be reloaded
+	// on SIGHUP or when the config or API keys file changes
+	settings := config.NewHolder(cfg)
+	reload := newReloader(args, settings, keyStore, exercises, log)
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	go func() {
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		for range hup {
+			log.Info("Received SIGHUP, reloading configuration")
+			reload()
+		}
+	}()
+	go config.WatchFiles(watchCtx, 2*time.Second, func() []string {
+		current := settings.Get()
+		return []string{current.File, current.APIKeysFile}
+	}, reload)
+
+	// Initialize Gin router with request IDs and structured access logs
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(logger.Middleware(log))
+	r.Use(appMetrics.Middleware())
+	r.Use(tracing.Middleware())
+	r.Use(gin.CustomRecovery(func(c *gin.Context, err any) {
+		logger.FromContext(c.Request.Context()).Error("Panic recovered", "error", err)
+		apierror.Abort(c, http.StatusInternalServerError, api.CodeInternal, "Internal server error")
+	}))
+
+	// Add CORS middleware
+	r.Use(func(c *gin.Context) {
+		origins := settings.Get().Origins()
+		if slices.Contains(origins, "*") {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else if origin := c.GetHeader("Origin"); slices.Contains(origins, origin) {
+			c.Header("Access-Control-Allow-Origin", origin)
+			c.Header("Vary", "Origin")
+		}
+		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, "+auth.HeaderName+", "+logger.RequestIDHeader)
+		c.Header("Access-Control-Expose-Headers", logger.RequestIDHeader)
+
+		if c.Request.Method == "OPTIONS" {
+			c.AbortWithStatus(204)
+			return
+		}
+
+		c.Next()
+	})
+
+	// Operational endpoints are registered without the API key middleware so
+	// scrapers and orchestration probes don't need a key
+	r.GET("/metrics", gin.WrapH(appMetrics.Handler()))
+
+	// API routes, under /api/v1 and the legacy /api prefix
+	readiness := health.New()
+	registerReadinessChecks(readiness, sandboxManager)
+	handlers := &apiServer{settings: settings, manager: sandboxManager, readiness: readiness, exercises: exercises, keys: keyStore}
+	authenticate := auth.Middleware(keyStore, func() bool { return settings.Get().RequireAPIKey })
+	if err := handlers.register(r, authenticate); err != nil {
+		log.Fatal("Failed to register API routes", "error", err)
+	}
+	r.NoRoute(apierror.NotFound)
+
+	// Start server
+	server := &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           r,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Info("Starting Yz Playground Backend", "port", cfg.Port)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	// Wait for a termination signal or a server failure
+	stopSignals := make(chan os.Signal, 1)
+	signal.Notify(stopSignals, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Failed to start server", "error", err)
+		}
+	case sig := <-stopSignals:
+		log.Info("Shutting down", "signal", sig.String(), "drain_timeout_ms", cfg.DrainTimeout)
+	}
+
+	// Stop taking executions and give running ones the drain period to finish;
+	// their HTTP responses are then written before the server closes
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), time.Duration(cfg.DrainTimeout)*time.Millisecond)
+	defer cancelDrain()
+	if err := sandboxManager.Shutdown(logger.NewContext(drainCtx, log)); err != nil {
+		log.Error("Sandbox shutdown failed", "error", err)
+	}
+
+	closeCtx, cancelClose := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelClose()
+	if err := server.Shutdown(closeCtx); err != nil {
+		log.Error("HTTP server shutdown failed", "error", err)
+	}
+	log.Info("Shutdown complete")
+	return 0
+}
+
+// registerReadinessChecks adds the checks that must pass before the backend takes traffic
+func registerReadinessChecks(checker *health.Checker, manager *sandbox.Manager) {
+	withSandbox := func(check func(*sandbox.Sandbox, context.Context) error) health.CheckFunc {
+		return func(ctx context.Context) error {
+			sb, err := manager.GetSandbox("default")
+			if err != nil {
+				return err
+			}
+			return check(sb, ctx)
+		}
+	}
+
+	checker.Register(health.Check{
+		Name: "accepting_executions",
+		Func: func(ctx context.Context) error {
+			if manager.Draining() {
+				return sandbox.ErrShuttingDown
+			}
+			return nil
+		},
+	})
+	checker.Register(health.Check{
+		Name: "docker",
+		Func: withSandbox((*sandbox.Sandbox).Ping),
+	})
+	checker.Register(health.Check{
+		Name: "sandbox_container",
+		Func: withSandbox((*sandbox.Sandbox).CheckContainer),
+	})
+	checker.Register(health.Check{
+		Name: "compiler",
+		Func: withSandbox(func(sb *sandbox.Sandbox, ctx context.Context) error {
+			_, err := sb.GetCompilerVersion(ctx, "")
+			return err
+		}),
+	})
+	// The canary compiles real code, so reuse its result rather than compiling
+	// on every probe. Busy slots show the sandbox is working, so the canary is
+	// skipped rather than failed when it can't get one.
+	checker.Register(health.Check{
+		Name: "canary",
+		Func: func(ctx context.Context) error {
+			err := manager.Canary(ctx)
+			if errors.Is(err, sandbox.ErrBusy) {
+				return fmt.Errorf("%w: %w", health.ErrSkipped, err)
+			}
+			return err
+		},
+		Timeout:  30 * time.Second,
+		CacheFor: 30 * time.Second,
+	})
+}
+
+// newReloader returns a function that reloads the configuration, API keys
+// and exercises, swapping in the settings that can change at runtime. All
+// three are loaded and checked before any is swapped in, so a reload that
+// fails anywhere changes nothing. Executions already running keep the limits
+// they started with.
+func newReloader(args []string, settings *config.Holder, keyStore *auth.Store, exercises *exercise.Store, log *logger.Logger) func() {
+	var mutex sync.Mutex
+
+	return func() {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		current := settings.Get()
+		next, err := config.LoadFlags(flag.NewFlagSet("yzplay serve", flag.ContinueOnError), args)
+		if err != nil {
+			log.Error("Configuration reload failed, keeping current settings", "error", err)
+			return
+		}
+
+		keys, err := auth.LoadKeys(next.APIKeysFile)
+		if err != nil {
+			log.Error("API keys reload failed, keeping current settings", "error", err)
+			return
+		}
+		loaded, err := exercise.Load(next.ExercisesDir)
+		if err != nil {
+			log.Error("Exercises reload failed, keeping current settings", "error", err)
+			return
+		}
+
+		for _, change := range config.Diff(current, next) {
+			if change.RequiresRestart {
+				log.Warn("Setting changed but needs a restart to take effect", "setting", change.Key, "old", change.Old, "new", change.New)
+			} else {
+				log.Info("Setting changed", "setting", change.Key, "old", change.Old, "new", change.New)
+			}
+		}
+
+		keyStore.Replace(keys)
+		exercises.Replace(loaded)
+		log.SetLevel(next.LogLevel)
+		settings.Swap(config.ApplyReloadable(current, next))
+		log.Info("Configuration reloaded", "api_keys", keyStore.Len(), "exercises", exercises.Len())
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"yz-playground/internal/apierror"
+	"yz-playground/internal/sandbox"
+	"yz-playground/internal/yz"
+	"yz-playground/internal/yztest"
+	"yz-playground/pkg/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+// runTests compiles code together with the request's tests, runs every test
+// and answers with a result per test
+func (s *apiServer) runTests(c *gin.Context) {
+	var req api.TestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidRequest(c, err)
+		return
+	}
+	opts, ok := s.limitOptions(c, req.Code+req.Tests, req.Files, req.Timeout, req.Memory)
+	if !ok {
+		return
+	}
+
+	suite, err := yztest.Build(req.Code, req.Tests)
+	if err != nil {
+		var syntaxErr *yz.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			c.JSON(http.StatusOK, api.TestResponse{
+				Tests:     []api.TestResult{},
+				Error:     yztest.TestsFile + ":" + syntaxErr.Error(),
+				ErrorCode: api.CodeSyntaxError,
+				Position:  position(syntaxErr.Pos),
+			})
+			return
+		}
+		apierror.AbortWithDetails(c, http.StatusBadRequest, api.CodeInvalidRequest, "Invalid tests",
+			map[string]any{"reason": err.Error()})
+		return
+	}
+
+	recorder := yztest.NewRecorder()
+	opts.Output = recorder
+	result, err := s.manager.ExecuteWithOptions(c.Request.Context(), suite.Source, opts)
+	if err != nil {
+		executeError(c, err)
+		return
+	}
+
+	results, output := suite.Results(recorder, stopReason(result))
+	resp := api.TestResponse{
+		Success:       result.Success,
+		Tests:         make([]api.TestResult, 0, len(results)),
+		Output:        output,
+		CompileTime:   result.CompileTime,
+		ExecutionTime: result.ExecutionTime,
+	}
+	if !result.Success {
+		resp.Error = suite.MapPositions(result.Error)
+		resp.ErrorCode = result.ErrorCode
+	}
+	for _, r := range results {
+		switch r.Status {
+		case yztest.StatusPassed:
+			resp.Passed++
+		case yztest.StatusFailed:
+			resp.Failed++
+			resp.Success = false
+		case yztest.StatusSkipped:
+			resp.Skipped++
+			resp.Success = false
+		}
+		resp.Tests = append(resp.Tests, api.TestResult{
+			Name:       r.Name,
+			Status:     r.Status,
+			DurationMs: milliseconds(r.Duration),
+			Message:    r.Message,
+			Output:     r.Output,
+		})
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// stopReason describes why a test program ended before running every test,
+// or returns "" if it ran to the end
+func stopReason(result *sandbox.ExecutionResult) string {
+	switch result.Outcome {
+	case sandbox.OutcomeSuccess:
+		return ""
+	case sandbox.OutcomeCompileError:
+		return "the tests didn't compile"
+	case sandbox.OutcomeRuntimeError:
+		return "the program crashed"
+	case sandbox.OutcomeTimeout:
+		return "the tests timed out"
+	case sandbox.OutcomeOOM:
+		return "the program ran out of memory"
+	case sandbox.OutcomeOutputLimit:
+		return "the output passed the size limit"
+	}
+	return "the run was stopped"
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// version is set at build time with -ldflags "-X main.version=..."
+var version = "dev"
+
+// runVersion prints the yzplay version, VCS revision and Go version
+func runVersion(args []string) int {
+	flags := flag.NewFlagSet("yzplay version", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return configError(err)
+	}
+
+	revision := "unknown"
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				revision = setting.Value
+			}
+		}
+	}
+
+	fmt.Printf("yzplay %s (revision %s, %s %s/%s)\n", version, revision, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	return 0
+}
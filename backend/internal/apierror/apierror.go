@@ -0,0 +1,51 @@
+package apierror
+
+import (
+	"net/http"
+
+	"yz-playground/internal/logger"
+	"yz-playground/pkg/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+// legacyKey is the gin context key marking requests to the legacy unversioned API
+const legacyKey = "apierror.legacy"
+
+// Legacy marks the requests it handles as calls to the legacy unversioned
+// API, whose errors keep their original shape, {"error": "message"}, for
+// existing clients
+func Legacy(c *gin.Context) {
+	c.Set(legacyKey, true)
+	c.Next()
+}
+
+// Body builds the error body for a request, tagged with its request ID
+func Body(c *gin.Context, code api.ErrorCode, message string, details map[string]any) api.ErrorBody {
+	return api.ErrorBody{
+		Code:      code,
+		Message:   message,
+		Details:   details,
+		RequestID: logger.RequestID(c.Request.Context()),
+	}
+}
+
+// Abort writes an error response and stops the handler chain
+func Abort(c *gin.Context, status int, code api.ErrorCode, message string) {
+	AbortWithDetails(c, status, code, message, nil)
+}
+
+// AbortWithDetails is like Abort with machine-readable details about the
+// error. Calls to the legacy API get the message alone.
+func AbortWithDetails(c *gin.Context, status int, code api.ErrorCode, message string, details map[string]any) {
+	if c.GetBool(legacyKey) {
+		c.AbortWithStatusJSON(status, gin.H{"error": message})
+		return
+	}
+	c.AbortWithStatusJSON(status, api.ErrorResponse{Error: Body(c, code, message, details)})
+}
+
+// NotFound answers requests that match no route
+func NotFound(c *gin.Context) {
+	Abort(c, http.StatusNotFound, api.CodeNotFound, "No route for "+c.Request.Method+" "+c.Request.URL.Path)
+}
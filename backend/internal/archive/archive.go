@@ -0,0 +1,335 @@
+// Package archive packs playground projects into zip and tar.gz archives and
+// unpacks archives into projects.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Archive formats
+const (
+	FormatZip   = "zip"
+	FormatTarGz = "tar.gz"
+)
+
+// Formats lists every archive format
+var Formats = []string{FormatZip, FormatTarGz}
+
+// MetaDir holds what an export adds besides the project's sources. Project
+// paths can't start with a dot, so it never clashes with a project file.
+const MetaDir = ".yzplay"
+
+// Paths of the files an export adds to MetaDir
+const (
+	GeneratedFile = MetaDir + "/main.go"
+	OutputFile    = MetaDir + "/output.txt"
+)
+
+// mainFile is the project's entry point, holding Project.Code
+const mainFile = "main.yz"
+
+// maxUnpacked bounds the bytes a tar.gz archive may decompress to, so a small
+// archive can't keep the server busy inflating gigabytes it then skips
+const maxUnpacked = 256 << 20
+
+var (
+	// ErrUnknownFormat is returned for data that is neither zip nor tar.gz
+	ErrUnknownFormat = errors.New("archive is neither zip nor tar.gz")
+	// ErrNoMain is returned for an archive without a main.yz to run
+	ErrNoMain = errors.New("archive has no main.yz at its root or in its only top-level directory")
+	// ErrTooLarge is returned when an archive's sources, or all it unpacks to,
+	// are larger than allowed
+	ErrTooLarge = errors.New("archive is larger than allowed")
+)
+
+// Project is a playground project
+type Project struct {
+	Code          string            // contents of main.yz
+	Files         map[string]string // other files by slash-separated relative path
+	GeneratedCode string            // Go code generated for the project, if any
+	Output        string            // output of the project's last run, if any
+}
+
+// entry is a file in an archive
+type entry struct {
+	name    string
+	content string
+}
+
+// entries lists the files of p in the order they are archived: main.yz, the
+// other files by path, then the generated code and output
+func (p Project) entries() []entry {
+	entries := []entry{{mainFile, p.Code}}
+	names := make([]string, 0, len(p.Files))
+	for name := range p.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		entries = append(entries, entry{name, p.Files[name]})
+	}
+	if p.GeneratedCode != "" {
+		entries = append(entries, entry{GeneratedFile, p.GeneratedCode})
+	}
+	if p.Output != "" {
+		entries = append(entries, entry{OutputFile, p.Output})
+	}
+	return entries
+}
+
+// Write writes p to w as an archive in format, with main.yz at its root
+func Write(w io.Writer, format string, p Project) error {
+	switch format {
+	case FormatZip:
+		return writeZip(w, p)
+	case FormatTarGz:
+		return writeTarGz(w, p)
+	}
+	return fmt.Errorf("unknown archive format %q", format)
+}
+
+// writeZip writes p as a zip archive
+func writeZip(w io.Writer, p Project) error {
+	zw := zip.NewWriter(w)
+	now := time.Now()
+	for _, e := range p.entries() {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: e.name, Method: zip.Deflate, Modified: now})
+		if err != nil {
+			return fmt.Errorf("failed to add %s: %w", e.name, err)
+		}
+		if _, err := io.WriteString(f, e.content); err != nil {
+			return fmt.Errorf("failed to write %s: %w", e.name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to close zip writer: %w", err)
+	}
+	return nil
+}
+
+// writeTarGz writes p as a gzip-compressed tar archive
+func writeTarGz(w io.Writer, p Project) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	now := time.Now()
+	for _, e := range p.entries() {
+		header := &tar.Header{Typeflag: tar.TypeReg, Name: e.name, Size: int64(len(e.content)), Mode: 0644, ModTime: now}
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to add %s: %w", e.name, err)
+		}
+		if _, err := io.WriteString(tw, e.content); err != nil {
+			return fmt.Errorf("failed to write %s: %w", e.name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to close tar writer: %w", err)
+	}
+	if err := gw.Close(); err != nil {
+		return fmt.Errorf("failed to close gzip writer: %w", err)
+	}
+	return nil
+}
+
+// Read unpacks a zip or tar.gz archive, telling them apart by their first
+// bytes. Only .yz files become part of the project; the paths of other files
+// are returned as skipped. If main.yz isn't at the root but the archive has a
+// single top-level directory holding it, as repository downloads do, paths
+// are taken relative to that directory. maxSize bounds the total size of the
+// .yz files.
+func Read(data []byte, maxSize int) (*Project, []string, error) {
+	var files map[string]string
+	var skipped []string
+	var err error
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")) || bytes.HasPrefix(data, []byte("PK\x05\x06")):
+		files, skipped, err = readZip(data, maxSize)
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		files, skipped, err = readTarGz(data, maxSize)
+	default:
+		return nil, nil, ErrUnknownFormat
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root, err := projectRoot(files)
+	if err != nil {
+		return nil, nil, err
+	}
+	p := &Project{Files: make(map[string]string)}
+	for name, content := range files {
+		name = strings.TrimPrefix(name, root)
+		if name == mainFile {
+			p.Code = content
+		} else {
+			p.Files[name] = content
+		}
+	}
+	sort.Strings(skipped)
+	return p, skipped, nil
+}
+
+// projectRoot returns the directory of files holding main.yz, with a
+// trailing slash, or "" for the root
+func projectRoot(files map[string]string) (string, error) {
+	if _, ok := files[mainFile]; ok {
+		return "", nil
+	}
+	var top string
+	for name := range files {
+		dir, _, found := strings.Cut(name, "/")
+		if !found || (top != "" && dir != top) {
+			return "", ErrNoMain
+		}
+		top = dir
+	}
+	if _, ok := files[top+"/"+mainFile]; top == "" || !ok {
+		return "", ErrNoMain
+	}
+	return top + "/", nil
+}
+
+// readZip returns the .yz files of a zip archive and the names of the others
+func readZip(data []byte, maxSize int) (map[string]string, []string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid zip archive: %w", err)
+	}
+
+	s := sources{files: make(map[string]string), remaining: maxSize}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		name, ok, err := s.include(f.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ok {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open %s: %w", f.Name, err)
+		}
+		err = s.add(name, rc)
+		rc.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return s.files, s.skipped, nil
+}
+
+// readTarGz returns the .yz files of a tar.gz archive and the names of the others
+func readTarGz(data []byte, maxSize int) (map[string]string, []string, error) {
+	gr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid gzip data: %w", err)
+	}
+	defer gr.Close()
+	tr := tar.NewReader(&limitedReader{r: gr, remaining: maxUnpacked})
+
+	s := sources{files: make(map[string]string), remaining: maxSize}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if errors.Is(err, ErrTooLarge) {
+				return nil, nil, err
+			}
+			return nil, nil, fmt.Errorf("invalid tar archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		name, ok, err := s.include(header.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ok {
+			if err := s.add(name, tr); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return s.files, s.skipped, nil
+}
+
+// sources collects the .yz files of an archive
+type sources struct {
+	files     map[string]string
+	skipped   []string
+	remaining int // bytes of sources still allowed
+}
+
+// include cleans the name of an archive file and reports whether the file is
+// a source to read. Other files are recorded as skipped, as are files in
+// hidden directories such as .git and the __MACOSX metadata of macOS zips.
+func (s *sources) include(name string) (string, bool, error) {
+	clean := path.Clean(name)
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", false, fmt.Errorf("archive entry %q points outside the archive", name)
+	}
+	hidden := false
+	for _, segment := range strings.Split(clean, "/") {
+		hidden = hidden || strings.HasPrefix(segment, ".") || segment == "__MACOSX"
+	}
+	if hidden || path.Ext(clean) != ".yz" {
+		s.skipped = append(s.skipped, clean)
+		return "", false, nil
+	}
+	if _, ok := s.files[clean]; ok {
+		return "", false, fmt.Errorf("archive has %s more than once", clean)
+	}
+	return clean, true, nil
+}
+
+// add reads the source name from r
+func (s *sources) add(name string, r io.Reader) error {
+	content, err := io.ReadAll(io.LimitReader(r, int64(s.remaining)+1))
+	if err != nil {
+		if errors.Is(err, ErrTooLarge) {
+			return err
+		}
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if len(content) > s.remaining {
+		return ErrTooLarge
+	}
+	s.remaining -= len(content)
+	s.files[name] = string(content)
+	return nil
+}
+
+// limitedReader reads from r until remaining bytes are read, then fails with
+// ErrTooLarge
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+// Read implements io.Reader
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		return 0, ErrTooLarge
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
@@ -0,0 +1,291 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"yz-playground/internal/apierror"
+	"yz-playground/internal/config"
+	"yz-playground/internal/logger"
+	"yz-playground/pkg/api"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// HeaderName is the request header carrying the API key
+const HeaderName = "X-API-Key"
+
+// QueryParam is the query parameter carrying the API key on WebSocket handshakes
+const QueryParam = "api_key"
+
+// contextKey is the gin context key holding the authenticated *Key
+const contextKey = "api_key"
+
+// Key describes an API key and the limits attached to it
+type Key struct {
+	Name             string `json:"name"`
+	Key              string `json:"key"`
+	RateLimit        int    `json:"rate_limit,omitempty"`         // requests per minute, 0 means unlimited
+	MaxExecutionTime int    `json:"max_execution_time,omitempty"` // in milliseconds, 0 means server default
+	MaxMemory        int    `json:"max_memory,omitempty"`         // in MB, 0 means server default
+}
+
+// keysFile is the on-disk layout of the API keys file
+type keysFile struct {
+	Keys []Key `json:"keys"`
+}
+
+// ExecutionTimeLimit returns the key's execution time limit in milliseconds, or def when unset
+func (k *Key) ExecutionTimeLimit(def int) int {
+	if k == nil || k.MaxExecutionTime == 0 {
+		return def
+	}
+	return k.MaxExecutionTime
+}
+
+// MemoryLimit returns the key's memory limit in MB, or def when unset
+func (k *Key) MemoryLimit(def int) int {
+	if k == nil || k.MaxMemory == 0 {
+		return def
+	}
+	return k.MaxMemory
+}
+
+// Store holds the known API keys and their rate limiters
+type Store struct {
+	keys     []*Key
+	limiters map[string]*rate.Limiter
+	mutex    sync.RWMutex
+}
+
+// NewStore creates a store from the given keys
+func NewStore(keys []Key) (*Store, error) {
+	store := &Store{
+		limiters: make(map[string]*rate.Limiter),
+	}
+
+	parsed, err := validateKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+	store.keys = parsed
+	return store, nil
+}
+
+// validateKeys checks keys for missing values, duplicate names or values, and
+// limits outside the bounds of the server's own
+func validateKeys(keys []Key) ([]*Key, error) {
+	var parsed []*Key
+	seenNames := make(map[string]bool)
+	seenValues := make(map[string]string)
+	for i := range keys {
+		key := keys[i]
+		if key.Name == "" {
+			return nil, fmt.Errorf("key %d has no name", i)
+		}
+		if key.Key == "" {
+			return nil, fmt.Errorf("key %q has an empty value", key.Name)
+		}
+		if seenNames[key.Name] {
+			return nil, fmt.Errorf("duplicate key name %q", key.Name)
+		}
+		if other, ok := seenValues[key.Key]; ok {
+			return nil, fmt.Errorf("keys %q and %q have the same value", other, key.Name)
+		}
+		if key.RateLimit < 0 {
+			return nil, fmt.Errorf("key %q has a negative rate_limit", key.Name)
+		}
+		if key.MaxExecutionTime != 0 && (key.MaxExecutionTime < config.MinExecutionTimeLimit || key.MaxExecutionTime > config.MaxExecutionTimeLimit) {
+			return nil, fmt.Errorf("key %q: max_execution_time must be between %d and %d ms, got %d",
+				key.Name, config.MinExecutionTimeLimit, config.MaxExecutionTimeLimit, key.MaxExecutionTime)
+		}
+		if key.MaxMemory != 0 && (key.MaxMemory < config.MinMemoryLimit || key.MaxMemory > config.MaxMemoryLimit) {
+			return nil, fmt.Errorf("key %q: max_memory must be between %d and %d MB, got %d",
+				key.Name, config.MinMemoryLimit, config.MaxMemoryLimit, key.MaxMemory)
+		}
+		seenNames[key.Name] = true
+		seenValues[key.Key] = key.Name
+		parsed = append(parsed, &key)
+	}
+
+	return parsed, nil
+}
+
+// readKeys reads the keys listed in a JSON file
+func readKeys(path string) ([]Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API keys file: %w", err)
+	}
+
+	var file keysFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse API keys file: %w", err)
+	}
+	return file.Keys, nil
+}
+
+// LoadKeys reads and checks the keys in path, or returns none when path is
+// empty, for Replace to swap in
+func LoadKeys(path string) ([]*Key, error) {
+	var keys []Key
+	if path != "" {
+		var err error
+		if keys, err = readKeys(path); err != nil {
+			return nil, err
+		}
+	}
+	return validateKeys(keys)
+}
+
+// Reload replaces the store's keys with those in path, or clears them when
+// path is empty. On error the current keys stay in place.
+func (s *Store) Reload(path string) error {
+	keys, err := LoadKeys(path)
+	if err != nil {
+		return err
+	}
+	s.Replace(keys)
+	return nil
+}
+
+// Replace swaps in keys loaded by LoadKeys. Rate limiter state is kept for
+// keys whose rate is unchanged.
+func (s *Store) Replace(keys []*Key) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	limiters := make(map[string]*rate.Limiter)
+	for _, key := range keys {
+		if limiter, exists := s.limiters[key.Name]; exists && limiter.Burst() == key.RateLimit {
+			limiters[key.Name] = limiter
+		}
+	}
+	s.keys = keys
+	s.limiters = limiters
+}
+
+// Lookup returns the key matching the given value, or nil
+func (s *Store) Lookup(value string) *Key {
+	if s == nil || value == "" {
+		return nil
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	// Compare every key in constant time so lookups don't leak key prefixes
+	var found *Key
+	for _, key := range s.keys {
+		if subtle.ConstantTimeCompare([]byte(key.Key), []byte(value)) == 1 {
+			found = key
+		}
+	}
+	return found
+}
+
+// Len returns the number of configured keys
+func (s *Store) Len() int {
+	if s == nil {
+		return 0
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.keys)
+}
+
+// Allow reports whether the key may make another request now, and if not,
+// how long to wait. Anonymous callers, with a nil key, are always allowed.
+func (s *Store) Allow(key *Key) (bool, time.Duration) {
+	if key == nil || key.RateLimit == 0 {
+		return true, 0
+	}
+
+	s.mutex.Lock()
+	limiter, exists := s.limiters[key.Name]
+	if !exists {
+		limiter = rate.NewLimiter(rate.Limit(float64(key.RateLimit)/60), key.RateLimit)
+		s.limiters[key.Name] = limiter
+	}
+	s.mutex.Unlock()
+
+	reservation := limiter.Reserve()
+	if delay := reservation.Delay(); delay > 0 {
+		reservation.Cancel()
+		return false, delay
+	}
+	return true, 0
+}
+
+// Middleware authenticates requests using the API key header.
+// Requests without a key are let through anonymously unless required reports true;
+// it is called per request so the policy can change at runtime.
+func Middleware(store *Store, required func() bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value := extractKey(c.Request)
+		if value == "" {
+			if required() {
+				apierror.Abort(c, http.StatusUnauthorized, api.CodeUnauthorized, "API key required")
+				return
+			}
+			c.Next()
+			return
+		}
+
+		key := store.Lookup(value)
+		if key == nil {
+			apierror.Abort(c, http.StatusUnauthorized, api.CodeUnauthorized, "Invalid API key")
+			return
+		}
+		c.Set(contextKey, key)
+		c.Request = c.Request.WithContext(logger.WithFields(c.Request.Context(), "api_key", key.Name))
+
+		if ok, delay := store.Allow(key); !ok {
+			retryAfter := int(delay.Seconds()) + 1
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			apierror.AbortWithDetails(c, http.StatusTooManyRequests, api.CodeRateLimited, "Rate limit exceeded",
+				map[string]any{"limit_per_minute": key.RateLimit, "retry_after_seconds": retryAfter})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// FromContext returns the authenticated key for the request, or nil for anonymous callers
+func FromContext(c *gin.Context) *Key {
+	if value, exists := c.Get(contextKey); exists {
+		if key, ok := value.(*Key); ok {
+			return key
+		}
+	}
+	return nil
+}
+
+// extractKey reads the API key from the X-API-Key header or a Bearer token.
+// Browsers can't set headers on WebSocket handshakes, so those may pass the
+// key in the query parameter instead.
+func extractKey(r *http.Request) string {
+	if value := strings.TrimSpace(r.Header.Get(HeaderName)); value != "" {
+		return value
+	}
+
+	authorization := r.Header.Get("Authorization")
+	if token, ok := strings.CutPrefix(authorization, "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return strings.TrimSpace(r.URL.Query().Get(QueryParam))
+	}
+	return ""
+}
@@ -8,6 +8,8 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+
+	"yz-playground/internal/logger"
 )
 
 // Compiler represents the Yz compiler wrapper
@@ -51,6 +53,7 @@ func (c *Compiler) Compile(ctx context.Context, code string) (*CompileResult, er
 	output, err := c.runCompilation(ctx, tempFile, executablePath)
 
 	compileTime := int(time.Since(startTime).Milliseconds())
+	logger.FromContext(ctx).Debug("Compilation finished", "success", err == nil, "compile_time_ms", compileTime)
 
 	result := &CompileResult{
 		Success:     err == nil,
@@ -106,6 +109,7 @@ func (c *Compiler) GetVersion(ctx context.Context) (string, error) {
 	cmd := exec.CommandContext(ctx, c.executablePath, "--version")
 	output, err := cmd.Output()
 	if err != nil {
+		logger.FromContext(ctx).Warn("Failed to get compiler version", "path", c.executablePath, "error", err)
 		return "", fmt.Errorf("failed to get version: %w", err)
 	}
 	return strings.TrimSpace(string(output)), nil
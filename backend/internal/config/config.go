@@ -1,48 +1,361 @@
 package config
 
 import (
+	"errors"
+	"flag"
+	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
+
+	"yz-playground/internal/logger"
+	"yz-playground/internal/tracing"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// Bounds of the execution time limit in milliseconds and the memory limit in
+// MB, for the server's limits and those of API keys alike
+const (
+	MinExecutionTimeLimit = 1000
+	MaxExecutionTimeLimit = 600000
+	MinMemoryLimit        = 16
+	MaxMemoryLimit        = 16384
 )
 
 // Config holds the application configuration
 type Config struct {
-	Port             string
-	MaxExecutionTime int
-	MaxMemory        int
-	MaxCodeSize      int
-	SandboxContainer string
-	YZCompilerPath   string
-	IsolateConfig    string
+	Port                    string
+	MaxExecutionTime        int // in milliseconds
+	MaxMemory               int // in MB
+	MaxCodeSize             int // in bytes
+	MaxOutputSize           int // in bytes
+	MaxConcurrentExecutions int
+	MaxQueuedExecutions     int
+	MaxProjectFiles         int // files a project may have besides main.yz
+	MaxPathDepth            int // directory levels in a project file path
+	MaxArchiveSize          int // in bytes, for project archive uploads
+	MaxBenchmarkRuns        int // runs a benchmark may ask for
+	SandboxContainer        string
+	SandboxImage            string
+	SandboxWorkDir          string
+	YZCompilerPath          string
+	CompilerVersions        string // comma-separated name=path pairs of further yzc builds in the container
+	APIKeysFile             string
+	ExercisesDir            string // directory of exercise files, none if empty
+	RequireAPIKey           bool
+	LogLevel                string
+	LogFormat               string
+	TraceExporter           string
+	AllowedOrigins          string // comma-separated, "*" allows any origin
+	DrainTimeout            int    // in milliseconds
+
+	// File is the configuration file the settings were loaded from, if any
+	File string
 }
 
-// Load loads configuration from environment variables
-func Load() *Config {
+// setting binds one configuration value to its file key, environment variable and flag
+type setting struct {
+	key        string // key in the config file and flag name with '_' replaced by '-'
+	env        string
+	usage      string
+	value      any  // *string, *int or *bool
+	reloadable bool // whether a running server picks up changes without a restart
+}
+
+// Default returns the built-in configuration
+func Default() *Config {
 	return &Config{
-		Port:             getEnv("PORT", "8080"),
-		MaxExecutionTime: getEnvAsInt("MAX_EXECUTION_TIME", 10000),
-		MaxMemory:        getEnvAsInt("MAX_MEMORY", 256),
-		MaxCodeSize:      getEnvAsInt("MAX_CODE_SIZE", 10000),
-		SandboxContainer: getEnv("SANDBOX_CONTAINER", "yz-sandbox"),
-		YZCompilerPath:   getEnv("YZ_COMPILER_PATH", "/usr/local/bin/yzc"),
-		IsolateConfig:    getEnv("ISOLATE_CONFIG", "/etc/isolate.conf"),
+		Port:                    "8080",
+		MaxExecutionTime:        10000,
+		MaxMemory:               256,
+		MaxCodeSize:             10000,
+		MaxOutputSize:           1 << 20,
+		MaxConcurrentExecutions: 1,
+		MaxQueuedExecutions:     16,
+		MaxProjectFiles:         16,
+		MaxPathDepth:            4,
+		MaxArchiveSize:          4 << 20,
+		MaxBenchmarkRuns:        20,
+		SandboxContainer:        "yz-sandbox",
+		SandboxImage:            "localhost/yz-sandbox",
+		SandboxWorkDir:          "/workspace",
+		YZCompilerPath:          "/usr/local/bin/yzc",
+		LogLevel:                "info",
+		LogFormat:               "json",
+		TraceExporter:           tracing.ExporterNone,
+		AllowedOrigins:          "*",
+		DrainTimeout:            30000,
+	}
+}
+
+// settings lists every configurable value of c
+func (c *Config) settings() []setting {
+	return []setting{
+		{"port", "PORT", "HTTP port to listen on", &c.Port, false},
+		{"max_execution_time", "MAX_EXECUTION_TIME", "maximum execution time in milliseconds", &c.MaxExecutionTime, true},
+		{"max_memory", "MAX_MEMORY", "memory limit per execution in MB; advisory, passed to the program as GOMEMLIMIT", &c.MaxMemory, true},
+		{"max_code_size", "MAX_CODE_SIZE", "maximum code size in bytes", &c.MaxCodeSize, true},
+		{"max_output_size", "MAX_OUTPUT_SIZE", "maximum output per execution in bytes; longer runs are stopped", &c.MaxOutputSize, true},
+		{"max_concurrent_executions", "MAX_CONCURRENT_EXECUTIONS", "executions allowed to run at once", &c.MaxConcurrentExecutions, false},
+		{"max_queued_executions", "MAX_QUEUED_EXECUTIONS", "executions allowed to wait for a free slot", &c.MaxQueuedExecutions, false},
+		{"max_project_files", "MAX_PROJECT_FILES", "files a project may have besides main.yz", &c.MaxProjectFiles, true},
+		{"max_path_depth", "MAX_PATH_DEPTH", "directory levels allowed in a project file path", &c.MaxPathDepth, true},
+		{"max_archive_size", "MAX_ARCHIVE_SIZE", "maximum size of an uploaded project archive in bytes", &c.MaxArchiveSize, true},
+		{"max_benchmark_runs", "MAX_BENCHMARK_RUNS", "runs a benchmark may ask for", &c.MaxBenchmarkRuns, true},
+		{"sandbox_container", "SANDBOX_CONTAINER", "name of the running sandbox container", &c.SandboxContainer, false},
+		{"sandbox_image", "SANDBOX_IMAGE", "image the sandbox container is created from if it doesn't exist", &c.SandboxImage, false},
+		{"sandbox_workdir", "SANDBOX_WORKDIR", "workspace directory inside the sandbox container", &c.SandboxWorkDir, false},
+		{"yz_compiler_path", "YZ_COMPILER_PATH", "path to yzc inside the sandbox container", &c.YZCompilerPath, false},
+		{"compiler_versions", "COMPILER_VERSIONS", "comma-separated name=path list of further yzc versions inside the sandbox container, chosen per request", &c.CompilerVersions, true},
+		{"api_keys_file", "API_KEYS_FILE", "JSON file with API keys", &c.APIKeysFile, true},
+		{"exercises_dir", "EXERCISES_DIR", "directory of exercise files", &c.ExercisesDir, true},
+		{"require_api_key", "REQUIRE_API_KEY", "reject requests without an API key", &c.RequireAPIKey, true},
+		{"allowed_origins", "ALLOWED_ORIGINS", "comma-separated CORS origins, * for any", &c.AllowedOrigins, true},
+		{"drain_timeout", "DRAIN_TIMEOUT", "how long shutdown waits for running executions, in milliseconds", &c.DrainTimeout, false},
+		{"log_level", "LOG_LEVEL", "log level (debug, info, warn, error)", &c.LogLevel, true},
+		{"log_format", "LOG_FORMAT", "log format (json, text)", &c.LogFormat, false},
+		{"trace_exporter", "TRACE_EXPORTER", "trace exporter (none, stdout, otlp)", &c.TraceExporter, false},
+	}
+}
+
+// Load builds the configuration from, in increasing precedence, the built-in
+// defaults, a YAML or TOML file, environment variables and command-line flags.
+// The file is taken from the -config flag or the CONFIG_FILE variable.
+func Load(args []string) (*Config, error) {
+	flags := flag.NewFlagSet("yzplay", flag.ContinueOnError)
+	cfg, err := LoadFlags(flags, args)
+	if err != nil {
+		return nil, err
+	}
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+	}
+	return cfg, nil
+}
+
+// LoadFlags is like Load but registers the settings on a caller's flag set,
+// so commands can add flags of their own and read positional arguments from
+// flags.Args() afterwards
+func LoadFlags(flags *flag.FlagSet, args []string) (*Config, error) {
+	cfg := Default()
+	settings := cfg.settings()
+
+	// Collect flags first so -config is known, but apply them last
+	configFile := flags.String("config", os.Getenv("CONFIG_FILE"), "configuration file (YAML or TOML)")
+	flagValues := make(map[string]string)
+	for _, s := range settings {
+		key := s.key
+		flags.Func(flagName(key), s.usage+" (env "+s.env+")", func(value string) error {
+			flagValues[key] = value
+			return nil
+		})
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			return nil, err
+		}
+		cfg.File = *configFile
+	}
+
+	var errs []error
+	for _, s := range settings {
+		if value := os.Getenv(s.env); value != "" {
+			if err := setValue(s.value, value); err != nil {
+				errs = append(errs, fmt.Errorf("environment variable %s: %w", s.env, err))
+			}
+		}
+	}
+	for _, s := range settings {
+		if value, ok := flagValues[s.key]; ok {
+			if err := setValue(s.value, value); err != nil {
+				errs = append(errs, fmt.Errorf("flag -%s: %w", flagName(s.key), err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadFile applies the settings found in a YAML or TOML file
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
 	}
+
+	values := make(map[string]any)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return fmt.Errorf("config file %s: unsupported format, use .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	known := make(map[string]setting)
+	for _, s := range c.settings() {
+		known[s.key] = s
+	}
+
+	// Sort keys so errors are reported in a stable order
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+		s, ok := known[key]
+		if !ok {
+			errs = append(errs, fmt.Errorf("config file %s: unknown setting %q", path, key))
+			continue
+		}
+		if err := setValue(s.value, fileValue(values[key])); err != nil {
+			errs = append(errs, fmt.Errorf("config file %s: %s: %w", path, key, err))
+		}
+	}
+	return errors.Join(errs...)
 }
 
-// getEnv gets an environment variable or returns a default value
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// fileValue converts a decoded file value to its string form, joining lists with commas
+func fileValue(value any) string {
+	if list, ok := value.([]any); ok {
+		items := make([]string, len(list))
+		for i, item := range list {
+			items[i] = fmt.Sprint(item)
+		}
+		return strings.Join(items, ",")
 	}
-	return defaultValue
+	return fmt.Sprint(value)
 }
 
-// getEnvAsInt gets an environment variable as integer or returns a default value
-func getEnvAsInt(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
+// Origins returns the allowed CORS origins
+func (c *Config) Origins() []string {
+	var origins []string
+	for _, origin := range strings.Split(c.AllowedOrigins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
 		}
 	}
-	return defaultValue
+	return origins
+}
+
+// Compilers returns the further compiler versions by name, mapped to the
+// path of their yzc inside the sandbox container
+func (c *Config) Compilers() map[string]string {
+	compilers, _ := parseCompilers(c.CompilerVersions)
+	return compilers
+}
+
+// parseCompilers parses a comma-separated list of name=path pairs
+func parseCompilers(list string) (map[string]string, error) {
+	compilers := make(map[string]string)
+	for _, entry := range strings.Split(list, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		name, path, ok := strings.Cut(entry, "=")
+		name, path = strings.TrimSpace(name), strings.TrimSpace(path)
+		switch {
+		case !ok || name == "":
+			return nil, fmt.Errorf("compiler_versions entry %q must be name=path", entry)
+		case !filepath.IsAbs(path):
+			return nil, fmt.Errorf("compiler_versions path of %q must be absolute, got %q", name, path)
+		case compilers[name] != "":
+			return nil, fmt.Errorf("compiler_versions lists %q more than once", name)
+		}
+		compilers[name] = path
+	}
+	return compilers, nil
+}
+
+// Validate checks that every setting is within its allowed range
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	port, err := strconv.Atoi(c.Port)
+	check(err == nil && port > 0 && port <= 65535, "port must be a number between 1 and 65535, got %q", c.Port)
+	check(c.MaxExecutionTime >= MinExecutionTimeLimit && c.MaxExecutionTime <= MaxExecutionTimeLimit,
+		"max_execution_time must be between %d and %d ms, got %d", MinExecutionTimeLimit, MaxExecutionTimeLimit, c.MaxExecutionTime)
+	check(c.MaxMemory >= MinMemoryLimit && c.MaxMemory <= MaxMemoryLimit,
+		"max_memory must be between %d and %d MB, got %d", MinMemoryLimit, MaxMemoryLimit, c.MaxMemory)
+	check(c.MaxCodeSize >= 1 && c.MaxCodeSize <= 10<<20, "max_code_size must be between 1 and %d bytes, got %d", 10<<20, c.MaxCodeSize)
+	check(c.MaxOutputSize >= 1024 && c.MaxOutputSize <= 64<<20,
+		"max_output_size must be between 1024 and %d bytes, got %d", 64<<20, c.MaxOutputSize)
+	check(c.MaxConcurrentExecutions >= 1 && c.MaxConcurrentExecutions <= 64,
+		"max_concurrent_executions must be between 1 and 64, got %d", c.MaxConcurrentExecutions)
+	check(c.MaxQueuedExecutions >= 0 && c.MaxQueuedExecutions <= 1024,
+		"max_queued_executions must be between 0 and 1024, got %d", c.MaxQueuedExecutions)
+	check(c.MaxProjectFiles >= 1 && c.MaxProjectFiles <= 1000, "max_project_files must be between 1 and 1000, got %d", c.MaxProjectFiles)
+	check(c.MaxPathDepth >= 1 && c.MaxPathDepth <= 32, "max_path_depth must be between 1 and 32, got %d", c.MaxPathDepth)
+	check(c.MaxArchiveSize >= 1024 && c.MaxArchiveSize <= 64<<20,
+		"max_archive_size must be between 1024 and %d bytes, got %d", 64<<20, c.MaxArchiveSize)
+	check(c.MaxBenchmarkRuns >= 1 && c.MaxBenchmarkRuns <= 1000, "max_benchmark_runs must be between 1 and 1000, got %d", c.MaxBenchmarkRuns)
+	check(c.DrainTimeout >= 0 && c.DrainTimeout <= 600000, "drain_timeout must be between 0 and 600000 ms, got %d", c.DrainTimeout)
+	check(c.SandboxContainer != "", "sandbox_container must not be empty")
+	check(c.SandboxImage != "", "sandbox_image must not be empty")
+	check(filepath.IsAbs(c.SandboxWorkDir), "sandbox_workdir must be an absolute path, got %q", c.SandboxWorkDir)
+	check(filepath.IsAbs(c.YZCompilerPath), "yz_compiler_path must be an absolute path, got %q", c.YZCompilerPath)
+	if _, err := parseCompilers(c.CompilerVersions); err != nil {
+		errs = append(errs, err)
+	}
+	check(!c.RequireAPIKey || c.APIKeysFile != "", "require_api_key needs api_keys_file to be set")
+	check(logger.ValidLevel(c.LogLevel), "log_level must be one of debug, info, warn, error, got %q", c.LogLevel)
+	check(c.LogFormat == "json" || c.LogFormat == "text", "log_format must be json or text, got %q", c.LogFormat)
+	check(tracing.ValidExporter(c.TraceExporter), "trace_exporter must be none, stdout or otlp, got %q", c.TraceExporter)
+	check(len(c.Origins()) > 0, "allowed_origins must list at least one origin or *")
+
+	return errors.Join(errs...)
+}
+
+// setValue parses value into the setting pointed to by target
+func setValue(target any, value string) error {
+	switch ptr := target.(type) {
+	case *string:
+		*ptr = value
+	case *int:
+		intValue, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		*ptr = intValue
+	case *bool:
+		boolValue, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		*ptr = boolValue
+	default:
+		return fmt.Errorf("unsupported setting type %T", target)
+	}
+	return nil
+}
+
+// flagName converts a setting key to its command-line flag name
+func flagName(key string) string {
+	return strings.ReplaceAll(key, "_", "-")
 }
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"sync/atomic"
+	"time"
+)
+
+// Holder gives concurrent readers the current configuration while allowing it
+// to be replaced atomically on reload
+type Holder struct {
+	current atomic.Pointer[Config]
+}
+
+// NewHolder creates a holder with an initial configuration
+func NewHolder(cfg *Config) *Holder {
+	h := &Holder{}
+	h.current.Store(cfg)
+	return h
+}
+
+// Get returns the current configuration; callers must not modify it
+func (h *Holder) Get() *Config {
+	return h.current.Load()
+}
+
+// Swap replaces the configuration and returns the previous one
+func (h *Holder) Swap(cfg *Config) *Config {
+	return h.current.Swap(cfg)
+}
+
+// Change describes one setting that differs between two configurations
+type Change struct {
+	Key             string
+	Old             string
+	New             string
+	RequiresRestart bool
+}
+
+// String formats the change for logs
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %q -> %q", c.Key, c.Old, c.New)
+}
+
+// Diff lists the settings that differ between old and new
+func Diff(old, new *Config) []Change {
+	oldSettings := old.settings()
+	newSettings := new.settings()
+
+	var changes []Change
+	for i, s := range oldSettings {
+		oldValue := reflect.ValueOf(s.value).Elem().Interface()
+		newValue := reflect.ValueOf(newSettings[i].value).Elem().Interface()
+		if oldValue != newValue {
+			changes = append(changes, Change{
+				Key:             s.key,
+				Old:             fmt.Sprint(oldValue),
+				New:             fmt.Sprint(newValue),
+				RequiresRestart: !s.reloadable,
+			})
+		}
+	}
+	return changes
+}
+
+// WatchFiles polls the files returned by paths and calls onChange when any of
+// them is modified, until ctx is done. paths is re-evaluated on every poll so
+// a reload can change which files are watched.
+func WatchFiles(ctx context.Context, interval time.Duration, paths func() []string, onChange func()) {
+	modTimes := statFiles(paths())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			current := statFiles(paths())
+			if !reflect.DeepEqual(current, modTimes) {
+				modTimes = current
+				onChange()
+			}
+		}
+	}
+}
+
+// statFiles returns the modification time of each existing, non-empty path
+func statFiles(paths []string) map[string]time.Time {
+	modTimes := make(map[string]time.Time)
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil {
+			modTimes[path] = info.ModTime()
+		}
+	}
+	return modTimes
+}
+
+// ApplyReloadable returns a copy of current with the reloadable settings taken
+// from next; settings that need a restart keep their current values
+func ApplyReloadable(current, next *Config) *Config {
+	merged := *current
+	mergedSettings := merged.settings()
+	for i, s := range next.settings() {
+		if s.reloadable {
+			reflect.ValueOf(mergedSettings[i].value).Elem().Set(reflect.ValueOf(s.value).Elem())
+		}
+	}
+	return &merged
+}
@@ -0,0 +1,211 @@
+// Package exercise loads programming exercises and checks program output
+// against their expected output. Each exercise is a YAML or TOML file in the
+// exercises directory, named after the exercise's ID, e.g. sum-two.yaml,
+// holding a prompt, starter code, limits and the cases a submission is run
+// on. Cases are hidden from users unless they are marked as samples.
+package exercise
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// Defaults and bounds of exercise settings
+const (
+	DefaultTimeLimit = 2000 // in milliseconds, per case
+	MaxCases         = 100
+)
+
+// idPattern matches exercise IDs, which come from file names and appear in URLs
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Exercise is a programming exercise
+type Exercise struct {
+	ID          string `yaml:"-" toml:"-"`
+	Title       string `yaml:"title" toml:"title"`
+	Prompt      string `yaml:"prompt" toml:"prompt"`             // Markdown
+	StarterCode string `yaml:"starter_code" toml:"starter_code"` // Yz code the editor starts with
+	TimeLimit   int    `yaml:"time_limit" toml:"time_limit"`     // in milliseconds per case, DefaultTimeLimit if 0
+	MemoryLimit int    `yaml:"memory_limit" toml:"memory_limit"` // in MB, the server's limit if 0
+	Cases       []Case `yaml:"cases" toml:"cases"`
+}
+
+// Case is an input a submission is run on and the output it must print
+type Case struct {
+	Stdin    string `yaml:"stdin" toml:"stdin"`
+	Expected string `yaml:"expected" toml:"expected"`
+	Sample   bool   `yaml:"sample" toml:"sample"` // shown with the exercise
+}
+
+// Samples returns the cases shown with the exercise
+func (e *Exercise) Samples() []Case {
+	var samples []Case
+	for _, c := range e.Cases {
+		if c.Sample {
+			samples = append(samples, c)
+		}
+	}
+	return samples
+}
+
+// Matches reports whether a program's output is the expected output. Line
+// endings, whitespace at the end of lines and blank lines at the end are
+// ignored.
+func Matches(output, expected string) bool {
+	return normalize(output) == normalize(expected)
+}
+
+// normalize drops what Matches ignores from output
+func normalize(output string) string {
+	lines := strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
+
+// Store holds the exercises loaded from a directory
+type Store struct {
+	exercises map[string]*Exercise
+	mutex     sync.RWMutex
+}
+
+// NewStore creates an empty store
+func NewStore() *Store {
+	return &Store{exercises: make(map[string]*Exercise)}
+}
+
+// Load reads and checks the exercises in dir, or returns none when dir is
+// empty, for Replace to swap in
+func Load(dir string) (map[string]*Exercise, error) {
+	if dir == "" {
+		return make(map[string]*Exercise), nil
+	}
+	return readDir(dir)
+}
+
+// Reload replaces the store's exercises with those in dir, or clears them
+// when dir is empty. On error the current exercises stay in place.
+func (s *Store) Reload(dir string) error {
+	exercises, err := Load(dir)
+	if err != nil {
+		return err
+	}
+	s.Replace(exercises)
+	return nil
+}
+
+// Replace swaps in exercises loaded by Load
+func (s *Store) Replace(exercises map[string]*Exercise) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.exercises = exercises
+}
+
+// Get returns the exercise with the given ID, or nil
+func (s *Store) Get(id string) *Exercise {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.exercises[id]
+}
+
+// List returns every exercise, by ID
+func (s *Store) List() []*Exercise {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	exercises := make([]*Exercise, 0, len(s.exercises))
+	for _, e := range s.exercises {
+		exercises = append(exercises, e)
+	}
+	sort.Slice(exercises, func(i, j int) bool { return exercises[i].ID < exercises[j].ID })
+	return exercises
+}
+
+// Len returns the number of exercises
+func (s *Store) Len() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.exercises)
+}
+
+// readDir reads every exercise file in dir. Files of other types are ignored.
+func readDir(dir string) (map[string]*Exercise, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read exercises directory: %w", err)
+	}
+
+	exercises := make(map[string]*Exercise)
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".toml") {
+			continue
+		}
+		e, err := readFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := exercises[e.ID]; ok {
+			return nil, fmt.Errorf("exercise %s is defined more than once", e.ID)
+		}
+		exercises[e.ID] = e
+	}
+	return exercises, nil
+}
+
+// readFile reads and checks one exercise file
+func readFile(path string) (*Exercise, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read exercise: %w", err)
+	}
+
+	var e Exercise
+	if strings.ToLower(filepath.Ext(path)) == ".toml" {
+		err = toml.Unmarshal(data, &e)
+	} else {
+		err = yaml.Unmarshal(data, &e)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse exercise %s: %w", path, err)
+	}
+
+	base := filepath.Base(path)
+	e.ID = strings.TrimSuffix(base, filepath.Ext(base))
+	if err := e.check(); err != nil {
+		return nil, fmt.Errorf("exercise %s: %w", path, err)
+	}
+	if e.TimeLimit == 0 {
+		e.TimeLimit = DefaultTimeLimit
+	}
+	return &e, nil
+}
+
+// check returns an error describing the first problem with e
+func (e *Exercise) check() error {
+	switch {
+	case !idPattern.MatchString(e.ID):
+		return errors.New("file name must be lowercase letters, digits, '-' and '_'")
+	case e.Title == "":
+		return errors.New("title is missing")
+	case len(e.Cases) == 0:
+		return errors.New("an exercise needs at least one case")
+	case len(e.Cases) > MaxCases:
+		return fmt.Errorf("an exercise may have at most %d cases, got %d", MaxCases, len(e.Cases))
+	case e.TimeLimit < 0:
+		return errors.New("time_limit can't be negative")
+	case e.MemoryLimit < 0:
+		return errors.New("memory_limit can't be negative")
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Check statuses
+const (
+	StatusPass = "pass"
+	StatusFail = "fail"
+	StatusSkip = "skip"
+)
+
+// ErrSkipped is returned, possibly wrapped, by a check that couldn't run
+// right now. A skipped check doesn't count against readiness and its result
+// isn't cached, so the next run tries again.
+var ErrSkipped = errors.New("check skipped")
+
+// defaultTimeout bounds a check that doesn't set its own timeout
+const defaultTimeout = 5 * time.Second
+
+// CheckFunc performs a check, returning an error if the dependency is unhealthy
+type CheckFunc func(ctx context.Context) error
+
+// Check describes a readiness check
+type Check struct {
+	Name     string
+	Func     CheckFunc
+	Timeout  time.Duration
+	CacheFor time.Duration // reuse the last result for this long, for expensive checks
+}
+
+// Result holds the outcome of a single check
+type Result struct {
+	Name      string
+	Status    string
+	Latency   time.Duration
+	Error     string
+	CheckedAt time.Time
+}
+
+// registeredCheck is a check plus its cached result
+type registeredCheck struct {
+	check  Check
+	mutex  sync.Mutex
+	last   Result
+	hasRun bool
+}
+
+// Checker runs a set of readiness checks
+type Checker struct {
+	checks []*registeredCheck
+}
+
+// New creates an empty checker
+func New() *Checker {
+	return &Checker{}
+}
+
+// Register adds a check
+func (c *Checker) Register(check Check) {
+	if check.Timeout <= 0 {
+		check.Timeout = defaultTimeout
+	}
+	c.checks = append(c.checks, &registeredCheck{check: check})
+}
+
+// Run runs all checks concurrently and reports whether every one passed.
+// Results are returned in registration order.
+func (c *Checker) Run(ctx context.Context) (bool, []Result) {
+	results := make([]Result, len(c.checks))
+
+	var wg sync.WaitGroup
+	for i, rc := range c.checks {
+		wg.Add(1)
+		go func(i int, rc *registeredCheck) {
+			defer wg.Done()
+			results[i] = rc.run(ctx)
+		}(i, rc)
+	}
+	wg.Wait()
+
+	healthy := true
+	for _, result := range results {
+		if result.Status == StatusFail {
+			healthy = false
+		}
+	}
+	return healthy, results
+}
+
+// run executes the check, or returns its cached result while still fresh
+func (rc *registeredCheck) run(ctx context.Context) Result {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	if rc.hasRun && rc.check.CacheFor > 0 && time.Since(rc.last.CheckedAt) < rc.check.CacheFor {
+		return rc.last
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, rc.check.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := rc.check.Func(checkCtx)
+
+	result := Result{
+		Name:      rc.check.Name,
+		Status:    StatusPass,
+		Latency:   time.Since(start),
+		CheckedAt: start,
+	}
+	if errors.Is(err, ErrSkipped) {
+		result.Status = StatusSkip
+		result.Error = err.Error()
+		return result
+	}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+
+	rc.last = result
+	rc.hasRun = true
+	return result
+}
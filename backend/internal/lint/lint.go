@@ -0,0 +1,89 @@
+// Package lint reports suspicious Yz code: bindings that are never used,
+// declarations that shadow outer ones, code that can't run and names that
+// don't follow Yz conventions.
+package lint
+
+import (
+	"sort"
+
+	"yz-playground/internal/yz"
+)
+
+// Rule names, as used to enable or disable rules
+const (
+	RuleUnused      = "unused"
+	RuleShadow      = "shadow"
+	RuleUnreachable = "unreachable"
+	RuleNaming      = "naming"
+)
+
+// Rules lists every rule
+var Rules = []string{RuleUnused, RuleShadow, RuleUnreachable, RuleNaming}
+
+// Warning is a problem found by a rule
+type Warning struct {
+	Rule    string
+	Message string
+	Pos     yz.Pos
+	End     yz.Pos // just past the code the warning is about
+	Fix     *Fix   // nil if the problem can't be fixed automatically
+}
+
+// Fix is a set of edits that resolves a warning
+type Fix struct {
+	Description string
+	Edits       []Edit // in source order, not overlapping
+}
+
+// Edit replaces the source between two positions
+type Edit struct {
+	Pos     yz.Pos
+	End     yz.Pos
+	NewText string
+}
+
+// Options configures a lint run
+type Options struct {
+	Rules map[string]bool // rules to enable or disable; rules not listed are enabled
+}
+
+// enabled reports whether the rule should run
+func (o Options) enabled(rule string) bool {
+	on, ok := o.Rules[rule]
+	return !ok || on
+}
+
+// file is the source being linted
+type file struct {
+	src     string
+	tokens  []yz.Token
+	root    *yz.Node
+	symbols []yz.Symbol
+}
+
+// rules maps rule names to their checks
+var rules = map[string]func(*file) []Warning{
+	RuleUnused:      unused,
+	RuleShadow:      shadow,
+	RuleUnreachable: unreachable,
+	RuleNaming:      naming,
+}
+
+// Lint checks src with the enabled rules and returns the warnings in source
+// order. Source that can't be parsed returns a *yz.SyntaxError.
+func Lint(src string, opts Options) ([]Warning, error) {
+	parsed, err := yz.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	f := &file{src: src, tokens: parsed.Tokens, root: parsed.Root, symbols: yz.Symbols(parsed.Tokens)}
+
+	var warnings []Warning
+	for _, rule := range Rules {
+		if opts.enabled(rule) {
+			warnings = append(warnings, rules[rule](f)...)
+		}
+	}
+	sort.SliceStable(warnings, func(i, j int) bool { return warnings[i].Pos.Offset < warnings[j].Pos.Offset })
+	return warnings, nil
+}
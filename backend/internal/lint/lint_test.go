@@ -0,0 +1,208 @@
+package lint
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"yz-playground/internal/yz"
+)
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		src   string
+		want  []string // rule pos-end message of each warning
+		fixed string   // src with every fix applied; src itself when nothing is fixable
+	}{
+		{
+			name:  "unused literal binding",
+			rule:  RuleUnused,
+			src:   "main : {\n    x : 1\n    println(2)\n}\n",
+			want:  []string{"unused 2:5-2:6 binding x is never used"},
+			fixed: "main : {\n    println(2)\n}\n",
+		},
+		{
+			name:  "unused binding at the end of the source",
+			rule:  RuleUnused,
+			src:   "main : {\n    x : \"a\"\n}",
+			want:  []string{"unused 2:5-2:6 binding x is never used"},
+			fixed: "main : {\n}",
+		},
+		{
+			name:  "unused binding with a call isn't removed",
+			rule:  RuleUnused,
+			src:   "main : {\n    x : f()\n    println(2)\n}\n",
+			want:  []string{"unused 2:5-2:6 binding x is never used"},
+			fixed: "main : {\n    x : f()\n    println(2)\n}\n",
+		},
+		{
+			name:  "unused parameter",
+			rule:  RuleUnused,
+			src:   "f #(a Int) {\n    println(1)\n}\n",
+			want:  []string{"unused 1:5-1:6 parameter a is never used"},
+			fixed: "f #(a Int) {\n    println(1)\n}\n",
+		},
+		{
+			name:  "underscore names are left alone",
+			rule:  RuleUnused,
+			src:   "main : {\n    _x : 1\n}\n",
+			fixed: "main : {\n    _x : 1\n}\n",
+		},
+		{
+			name:  "members count as used",
+			rule:  RuleUnused,
+			src:   "p : {\n    x : 1\n}\nmain : {\n    println(p.x)\n}\n",
+			fixed: "p : {\n    x : 1\n}\nmain : {\n    println(p.x)\n}\n",
+		},
+		{
+			name:  "shadowed top-level declaration",
+			rule:  RuleShadow,
+			src:   "x : 1\nmain : {\n    x : 2\n    println(x)\n}\n",
+			want:  []string{"shadow 3:5-3:6 x shadows the declaration at 1:1"},
+			fixed: "x : 1\nmain : {\n    x : 2\n    println(x)\n}\n",
+		},
+		{
+			name:  "shadowed local declaration",
+			rule:  RuleShadow,
+			src:   "main : {\n    x : 1\n    f : {\n        x : 2\n        println(x)\n    }\n    f()\n    println(x)\n}\n",
+			want:  []string{"shadow 4:9-4:10 x shadows the declaration at 2:5"},
+			fixed: "main : {\n    x : 1\n    f : {\n        x : 2\n        println(x)\n    }\n    f()\n    println(x)\n}\n",
+		},
+		{
+			name:  "unreachable lines after return",
+			rule:  RuleUnreachable,
+			src:   "main : {\n    return 1\n    println(2)\n    println(3)\n}\n",
+			want:  []string{"unreachable 3:5-4:15 unreachable code after return"},
+			fixed: "main : {\n    return 1\n}\n",
+		},
+		{
+			name:  "unreachable code on the same line",
+			rule:  RuleUnreachable,
+			src:   "main : {\n    break; println(2) }\n",
+			want:  []string{"unreachable 2:12-2:22 unreachable code after break"},
+			fixed: "main : {\n    break; }\n",
+		},
+		{
+			name:  "capitalized value",
+			rule:  RuleNaming,
+			src:   "main : {\n    Count : 1\n    println(Count)\n}\n",
+			want:  []string{"naming 2:5-2:10 Count should start with a lowercase letter, since capitalized names are types"},
+			fixed: "main : {\n    count : 1\n    println(count)\n}\n",
+		},
+		{
+			name:  "type name with underscores",
+			rule:  RuleNaming,
+			src:   "My_Type : {\n    a Int\n}\n",
+			want:  []string{"naming 1:1-1:8 type name My_Type should be CamelCase"},
+			fixed: "MyType : {\n    a Int\n}\n",
+		},
+		{
+			name:  "rename to a name in use isn't offered",
+			rule:  RuleNaming,
+			src:   "main : {\n    X : 1\n    x : 2\n    println(X + x)\n}\n",
+			want:  []string{"naming 2:5-2:6 X should start with a lowercase letter, since capitalized names are types"},
+			fixed: "main : {\n    X : 1\n    x : 2\n    println(X + x)\n}\n",
+		},
+		{
+			name:  "binding to a type",
+			rule:  RuleNaming,
+			src:   "main : {\n    N : Int\n    println(N)\n}\n",
+			fixed: "main : {\n    N : Int\n    println(N)\n}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings, err := Lint(tt.src, onlyRule(tt.rule))
+			if err != nil {
+				t.Fatalf("Lint: %v", err)
+			}
+			var got []string
+			for _, w := range warnings {
+				got = append(got, w.Rule+" "+w.Pos.String()+"-"+w.End.String()+" "+w.Message)
+				if w.Fix != nil {
+					checkPositions(t, tt.src, w.Fix.Edits)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("warnings\n got %q\nwant %q", got, tt.want)
+			}
+			if fixed := applyFixes(tt.src, warnings); fixed != tt.fixed {
+				t.Errorf("fixed source\n got %q\nwant %q", fixed, tt.fixed)
+			}
+		})
+	}
+}
+
+func TestOptions(t *testing.T) {
+	src := "main : {\n    Count : 1\n    return 1\n    println(2)\n}\n"
+	tests := []struct {
+		name  string
+		rules map[string]bool
+		want  []string
+	}{
+		{"all rules by default", nil, []string{RuleUnused, RuleNaming, RuleUnreachable}},
+		{"disabled rule", map[string]bool{RuleNaming: false}, []string{RuleUnused, RuleUnreachable}},
+		{"explicitly enabled rule", map[string]bool{RuleUnused: true, RuleNaming: false, RuleUnreachable: false}, []string{RuleUnused}},
+	}
+	for _, tt := range tests {
+		warnings, err := Lint(src, Options{Rules: tt.rules})
+		if err != nil {
+			t.Fatalf("Lint: %v", err)
+		}
+		var got []string
+		for _, w := range warnings {
+			got = append(got, w.Rule)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: rules %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSyntaxError(t *testing.T) {
+	if _, err := Lint("main : {", Options{}); err == nil || !strings.Contains(err.Error(), "unclosed {") {
+		t.Errorf("Lint of unbalanced source: error = %v, want unclosed {", err)
+	}
+}
+
+// onlyRule returns options enabling rule alone
+func onlyRule(rule string) Options {
+	rules := make(map[string]bool)
+	for _, r := range Rules {
+		rules[r] = r == rule
+	}
+	return Options{Rules: rules}
+}
+
+// applyFixes applies the edits of every warning's fix to src, last first so
+// earlier offsets stay valid
+func applyFixes(src string, warnings []Warning) string {
+	var edits []Edit
+	for _, w := range warnings {
+		if w.Fix != nil {
+			edits = append(edits, w.Fix.Edits...)
+		}
+	}
+	slices.SortFunc(edits, func(a, b Edit) int { return b.Pos.Offset - a.Pos.Offset })
+	for _, e := range edits {
+		src = src[:e.Pos.Offset] + e.NewText + src[e.End.Offset:]
+	}
+	return src
+}
+
+// checkPositions reports edits whose line and column don't match their offset
+func checkPositions(t *testing.T, src string, edits []Edit) {
+	t.Helper()
+	for _, e := range edits {
+		for _, pos := range []yz.Pos{e.Pos, e.End} {
+			before := src[:pos.Offset]
+			line := strings.Count(before, "\n") + 1
+			column := len([]rune(before[strings.LastIndexByte(before, '\n')+1:])) + 1
+			if pos.Line != line || pos.Column != column {
+				t.Errorf("edit position %s has offset %d, which is at %d:%d", pos, pos.Offset, line, column)
+			}
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"yz-playground/internal/yz"
+)
+
+// unused reports bindings and parameters declared in a block and never used.
+// Top-level declarations are left alone since they make up the program, and so
+// are names starting with an underscore. Typed declarations without a value,
+// such as fields, and names used anywhere as a member, as in p.x, count as
+// used since blocks expose their bindings.
+func unused(f *file) []Warning {
+	members := make(map[string]bool)
+	for i, tok := range f.tokens {
+		if tok.Kind == yz.Ident && i > 0 && f.tokens[i-1].Kind == yz.Dot {
+			members[tok.Text] = true
+		}
+	}
+
+	var warnings []Warning
+	for _, sym := range f.symbols {
+		if sym.Global || strings.HasPrefix(sym.Name, "_") || members[sym.Name] || !f.isBinding(sym) ||
+			len(yz.References(f.tokens, f.symbols, sym)) > 0 {
+			continue
+		}
+		what := "binding"
+		if sym.Kind == yz.SymbolParam {
+			what = "parameter"
+		}
+		warnings = append(warnings, Warning{
+			Rule:    RuleUnused,
+			Message: fmt.Sprintf("%s %s is never used", what, sym.Name),
+			Pos:     sym.Pos,
+			End:     nameEnd(sym),
+			Fix:     f.removeBinding(sym),
+		})
+	}
+	return warnings
+}
+
+// isBinding reports whether sym is a parameter or is bound to a value with a colon
+func (f *file) isBinding(sym yz.Symbol) bool {
+	if sym.Kind == yz.SymbolParam {
+		return true
+	}
+	i := f.index(sym.Pos)
+	return i >= 0 && i+1 < len(f.tokens) && f.tokens[i+1].Kind == yz.Colon
+}
+
+// removeBinding returns a fix deleting the line that binds sym to a literal,
+// or nil if the binding doesn't have a line of its own or its value might
+// have effects
+func (f *file) removeBinding(sym yz.Symbol) *Fix {
+	i := f.index(sym.Pos)
+	if i < 0 || i+3 >= len(f.tokens) || !f.leadsLine(sym.Pos) {
+		return nil
+	}
+	colon, value, after := f.tokens[i+1], f.tokens[i+2], f.tokens[i+3]
+	if colon.Kind != yz.Colon || (value.Kind != yz.Number && value.Kind != yz.String) {
+		return nil
+	}
+	switch after.Kind {
+	case yz.Newline:
+		return &Fix{Description: "Remove " + sym.Name, Edits: []Edit{{Pos: f.lineStart(sym.Pos), End: after.End}}}
+	case yz.EOF:
+		return &Fix{Description: "Remove " + sym.Name, Edits: []Edit{{Pos: f.lineStart(sym.Pos), End: value.End}}}
+	}
+	return nil
+}
+
+// shadow reports declarations that hide one of the same name from an
+// enclosing scope
+func shadow(f *file) []Warning {
+	var warnings []Warning
+	for _, sym := range f.symbols {
+		if sym.Global || strings.HasPrefix(sym.Name, "_") {
+			continue
+		}
+		var outer *yz.Symbol
+		for j := range f.symbols {
+			o := &f.symbols[j]
+			if o.Name != sym.Name || o.Scope.Start.Offset >= sym.Scope.Start.Offset || !o.Scope.Contains(sym.Pos.Offset) {
+				continue
+			}
+			// Top-level declarations are visible throughout; others only after they are made
+			if !o.Global && o.Pos.Offset > sym.Pos.Offset {
+				continue
+			}
+			if outer == nil || o.Scope.Start.Offset > outer.Scope.Start.Offset ||
+				(o.Scope.Start.Offset == outer.Scope.Start.Offset && o.Pos.Offset > outer.Pos.Offset) {
+				outer = o
+			}
+		}
+		if outer != nil {
+			warnings = append(warnings, Warning{
+				Rule:    RuleShadow,
+				Message: fmt.Sprintf("%s shadows the declaration at %s", sym.Name, outer.Pos),
+				Pos:     sym.Pos,
+				End:     nameEnd(sym),
+			})
+		}
+	}
+	return warnings
+}
+
+// unreachable reports statements that follow a return, break or continue in
+// the same block
+func unreachable(f *file) []Warning {
+	var warnings []Warning
+	yz.Walk(f.root, func(n *yz.Node) bool {
+		if n.Kind != yz.NodeBlock {
+			return true
+		}
+		for i, stmt := range n.Children[:max(len(n.Children)-1, 0)] {
+			if stmt.Kind == yz.NodeJump {
+				warnings = append(warnings, f.unreachableWarning(stmt.Text, n.Children[i+1:], n))
+				break
+			}
+		}
+		return true
+	})
+	return warnings
+}
+
+// unreachableWarning reports the dead statements at the end of block, after
+// the jump statement
+func (f *file) unreachableWarning(jump string, dead []*yz.Node, block *yz.Node) Warning {
+	start, end := dead[0].Span.Start, dead[len(dead)-1].Span.End
+	brace := block.Span.End
+	brace.Offset--
+	brace.Column--
+
+	edit := Edit{Pos: start, End: brace}
+	if f.leadsLine(start) {
+		edit.Pos = f.lineStart(start)
+	}
+	if f.leadsLine(brace) {
+		edit.End = f.lineStart(brace)
+	}
+	return Warning{
+		Rule:    RuleUnreachable,
+		Message: fmt.Sprintf("unreachable code after %s", jump),
+		Pos:     start,
+		End:     end,
+		Fix:     &Fix{Description: "Remove unreachable code", Edits: []Edit{edit}},
+	}
+}
+
+// naming reports names that don't follow Yz conventions: values and
+// parameters start with a lowercase letter, since capitalized names are
+// types, and type names are CamelCase
+func naming(f *file) []Warning {
+	var warnings []Warning
+	for _, sym := range f.symbols {
+		if strings.HasPrefix(sym.Name, "_") {
+			continue
+		}
+		capitalized := isUpper(sym.Name)
+		var message, name string
+		switch {
+		case capitalized && f.isValue(sym):
+			message = fmt.Sprintf("%s should start with a lowercase letter, since capitalized names are types", sym.Name)
+			name = lowerName(sym.Name)
+		case capitalized && sym.Kind == yz.SymbolBlock && strings.Contains(sym.Name, "_"):
+			message = fmt.Sprintf("type name %s should be CamelCase", sym.Name)
+			name = camelName(sym.Name)
+		default:
+			continue
+		}
+		warnings = append(warnings, Warning{
+			Rule:    RuleNaming,
+			Message: message,
+			Pos:     sym.Pos,
+			End:     nameEnd(sym),
+			Fix:     f.rename(sym, name),
+		})
+	}
+	return warnings
+}
+
+// isValue reports whether sym declares a value rather than a type: a
+// parameter, a typed value, or a binding to something other than a type
+func (f *file) isValue(sym yz.Symbol) bool {
+	if sym.Kind == yz.SymbolParam {
+		return true
+	}
+	i := f.index(sym.Pos)
+	if sym.Kind != yz.SymbolValue || i < 0 || i+2 >= len(f.tokens) {
+		return false
+	}
+	switch next, value := f.tokens[i+1], f.tokens[i+2]; next.Kind {
+	case yz.Ident, yz.LBracket:
+		return true
+	case yz.Colon:
+		return value.Kind != yz.Ident || !isUpper(value.Text)
+	}
+	return false
+}
+
+// rename returns a fix renaming sym and its uses, or nil if the new name is
+// already taken or empty
+func (f *file) rename(sym yz.Symbol, name string) *Fix {
+	if name == "" || name == sym.Name {
+		return nil
+	}
+	for _, tok := range f.tokens {
+		if tok.Kind == yz.Ident && tok.Text == name {
+			return nil
+		}
+	}
+
+	edits := []Edit{{Pos: sym.Pos, End: nameEnd(sym), NewText: name}}
+	for _, ref := range yz.References(f.tokens, f.symbols, sym) {
+		edits = append(edits, Edit{Pos: ref.Pos, End: ref.End, NewText: name})
+	}
+	sort.Slice(edits, func(i, j int) bool { return edits[i].Pos.Offset < edits[j].Pos.Offset })
+	return &Fix{Description: fmt.Sprintf("Rename %s to %s", sym.Name, name), Edits: edits}
+}
+
+// lowerName lowercases the first letter of name, or all of it if it's all capitals
+func lowerName(name string) string {
+	if strings.ToUpper(name) == name {
+		return strings.ToLower(name)
+	}
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToLower(r)) + name[size:]
+}
+
+// camelName joins the underscore-separated parts of name, capitalizing each
+func camelName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		r, size := utf8.DecodeRuneInString(part)
+		if size > 0 {
+			b.WriteRune(unicode.ToUpper(r))
+			b.WriteString(part[size:])
+		}
+	}
+	return b.String()
+}
+
+// isUpper reports whether name starts with a capital letter
+func isUpper(name string) bool {
+	r, _ := utf8.DecodeRuneInString(name)
+	return unicode.IsUpper(r)
+}
+
+// nameEnd returns the position just past the declared name of sym
+func nameEnd(sym yz.Symbol) yz.Pos {
+	end := sym.Pos
+	end.Offset += len(sym.Name)
+	end.Column += utf8.RuneCountInString(sym.Name)
+	return end
+}
+
+// index returns the index of the token at pos, or -1
+func (f *file) index(pos yz.Pos) int {
+	i := sort.Search(len(f.tokens), func(i int) bool { return f.tokens[i].Pos.Offset >= pos.Offset })
+	if i < len(f.tokens) && f.tokens[i].Pos.Offset == pos.Offset {
+		return i
+	}
+	return -1
+}
+
+// lineStart returns the start of the line containing pos
+func (f *file) lineStart(pos yz.Pos) yz.Pos {
+	return yz.Pos{Offset: strings.LastIndexByte(f.src[:pos.Offset], '\n') + 1, Line: pos.Line, Column: 1}
+}
+
+// leadsLine reports whether only spaces come before pos on its line
+func (f *file) leadsLine(pos yz.Pos) bool {
+	return strings.TrimSpace(f.src[f.lineStart(pos).Offset:pos.Offset]) == ""
+}
@@ -1,42 +1,160 @@
 package logger
 
 import (
-	"log"
+	"context"
+	"io"
+	"log/slog"
 	"os"
+	"strings"
 )
 
-// Logger wraps the standard log package with structured logging
+// Logger wraps slog with leveled, structured logging
 type Logger struct {
-	infoLogger  *log.Logger
-	errorLogger *log.Logger
-	debugLogger *log.Logger
+	logger *slog.Logger
+	level  *slog.LevelVar
 }
 
+// Options holds logger configuration
+type Options struct {
+	Level  string    // debug, info, warn or error
+	Format string    // json or text
+	Output io.Writer // defaults to stdout
+}
+
+// contextKey is the type for values this package stores in a context
+type contextKey int
+
+const (
+	loggerKey contextKey = iota
+	requestIDKey
+)
+
+var defaultLogger = New(Options{})
+
 // New creates a new logger instance
-func New() *Logger {
-	return &Logger{
-		infoLogger:  log.New(os.Stdout, "INFO: ", log.LstdFlags|log.Lshortfile),
-		errorLogger: log.New(os.Stderr, "ERROR: ", log.LstdFlags|log.Lshortfile),
-		debugLogger: log.New(os.Stdout, "DEBUG: ", log.LstdFlags|log.Lshortfile),
+func New(opts Options) *Logger {
+	output := opts.Output
+	if output == nil {
+		output = os.Stdout
+	}
+
+	level := &slog.LevelVar{}
+	level.Set(parseLevel(opts.Level))
+	handlerOpts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	if strings.EqualFold(opts.Format, "text") {
+		handler = slog.NewTextHandler(output, handlerOpts)
+	} else {
+		handler = slog.NewJSONHandler(output, handlerOpts)
+	}
+
+	return &Logger{logger: slog.New(handler), level: level}
+}
+
+// SetLevel changes the minimum level logged by l and every logger derived from it
+func (l *Logger) SetLevel(level string) {
+	l.level.Set(parseLevel(level))
+}
+
+// ValidLevel reports whether level is a recognised log level
+func ValidLevel(level string) bool {
+	switch strings.ToLower(level) {
+	case "debug", "info", "warn", "warning", "error":
+		return true
 	}
+	return false
+}
+
+// parseLevel converts a level name to a slog level, defaulting to info
+func parseLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// SetDefault sets the logger returned when a context carries none
+func SetDefault(l *Logger) {
+	defaultLogger = l
+	slog.SetDefault(l.logger)
 }
 
-// Info logs an info message
-func (l *Logger) Info(msg string, v ...interface{}) {
-	l.infoLogger.Printf(msg, v...)
+// Default returns the process-wide logger
+func Default() *Logger {
+	return defaultLogger
 }
 
-// Error logs an error message
-func (l *Logger) Error(msg string, v ...interface{}) {
-	l.errorLogger.Printf(msg, v...)
+// With returns a logger that adds the given key/value fields to every entry
+func (l *Logger) With(args ...any) *Logger {
+	return &Logger{logger: l.logger.With(args...), level: l.level}
 }
 
-// Debug logs a debug message
-func (l *Logger) Debug(msg string, v ...interface{}) {
-	l.debugLogger.Printf(msg, v...)
+// Info logs an info message with key/value fields
+func (l *Logger) Info(msg string, args ...any) {
+	l.logger.Info(msg, args...)
 }
 
-// Fatal logs a fatal error and exits
-func (l *Logger) Fatal(msg string, v ...interface{}) {
-	l.errorLogger.Fatalf(msg, v...)
+// Warn logs a warning message with key/value fields
+func (l *Logger) Warn(msg string, args ...any) {
+	l.logger.Warn(msg, args...)
+}
+
+// Error logs an error message with key/value fields
+func (l *Logger) Error(msg string, args ...any) {
+	l.logger.Error(msg, args...)
+}
+
+// Debug logs a debug message with key/value fields
+func (l *Logger) Debug(msg string, args ...any) {
+	l.logger.Debug(msg, args...)
+}
+
+// Fatal logs an error message and exits
+func (l *Logger) Fatal(msg string, args ...any) {
+	l.logger.Error(msg, args...)
+	os.Exit(1)
+}
+
+// Slog returns the underlying slog logger
+func (l *Logger) Slog() *slog.Logger {
+	return l.logger
+}
+
+// NewContext returns a context carrying the given logger
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, l)
+}
+
+// FromContext returns the logger carried by ctx, or the default logger
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(loggerKey).(*Logger); ok {
+		return l
+	}
+	return defaultLogger
+}
+
+// WithFields returns a context whose logger adds the given key/value fields
+func WithFields(ctx context.Context, args ...any) context.Context {
+	return NewContext(ctx, FromContext(ctx).With(args...))
+}
+
+// WithRequestID returns a context carrying the request ID and a logger tagged with it
+func WithRequestID(ctx context.Context, id string) context.Context {
+	ctx = context.WithValue(ctx, requestIDKey, id)
+	return WithFields(ctx, "request_id", id)
+}
+
+// RequestID returns the request ID carried by ctx, or an empty string
+func RequestID(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIDKey).(string); ok {
+		return id
+	}
+	return ""
 }
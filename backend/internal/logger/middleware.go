@@ -0,0 +1,65 @@
+package logger
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader is the header used to accept and return request IDs
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds request IDs supplied by clients
+const maxRequestIDLength = 128
+
+// Middleware assigns a request ID, attaches a request-scoped logger to the
+// request context and writes one access log entry per request
+func Middleware(l *Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		id := c.GetHeader(RequestIDHeader)
+		if id == "" || len(id) > maxRequestIDLength {
+			id = NewRequestID()
+		}
+		c.Header(RequestIDHeader, id)
+
+		ctx := NewContext(c.Request.Context(), l)
+		c.Request = c.Request.WithContext(WithRequestID(ctx, id))
+
+		c.Next()
+
+		// Use the final request context so fields added by later middleware are included
+		entry := FromContext(c.Request.Context()).With(
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"status", c.Writer.Status(),
+			"duration_ms", time.Since(start).Milliseconds(),
+			"client_ip", c.ClientIP(),
+			"bytes", c.Writer.Size(),
+		)
+		if len(c.Errors) > 0 {
+			entry = entry.With("errors", c.Errors.String())
+		}
+
+		switch status := c.Writer.Status(); {
+		case status >= 500:
+			entry.Error("request completed")
+		case status >= 400:
+			entry.Warn("request completed")
+		default:
+			entry.Info("request completed")
+		}
+	}
+}
+
+// NewRequestID generates a random request ID
+func NewRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(b)
+}
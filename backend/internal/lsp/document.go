@@ -0,0 +1,98 @@
+package lsp
+
+import (
+	"sort"
+	"unicode/utf16"
+
+	"yz-playground/internal/yz"
+)
+
+// document is a snapshot of an open text document. A change replaces the
+// snapshot, so one can be checked in the background while edits continue.
+type document struct {
+	uri       string
+	version   int
+	text      string
+	lines     []int // byte offset of the start of each line
+	tokens    []yz.Token
+	symbols   []yz.Symbol
+	syntaxErr *yz.SyntaxError // nil if the text tokenizes and its brackets balance
+}
+
+// newDocument analyzes a version of a document
+func newDocument(uri string, version int, text string) *document {
+	d := &document{uri: uri, version: version, text: text, lines: []int{0}}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			d.lines = append(d.lines, i+1)
+		}
+	}
+
+	// Tokens read before an error still give symbols for hover and completion
+	d.tokens, _ = yz.Tokenize(text)
+	d.symbols = yz.Symbols(d.tokens)
+	if err, ok := yz.Check(text).(*yz.SyntaxError); ok {
+		d.syntaxErr = err
+	}
+	return d
+}
+
+// offset converts a position to a byte offset, clamped to the document
+func (d *document) offset(pos Position) int {
+	if pos.Line < 0 {
+		return 0
+	}
+	if pos.Line >= len(d.lines) {
+		return len(d.text)
+	}
+	start, end := d.lines[pos.Line], len(d.text)
+	if pos.Line+1 < len(d.lines) {
+		end = d.lines[pos.Line+1] - 1
+	}
+
+	units := 0
+	for i, r := range d.text[start:end] {
+		if units >= pos.Character {
+			return start + i
+		}
+		units += utf16.RuneLen(r)
+	}
+	return end
+}
+
+// position converts a byte offset to a position
+func (d *document) position(offset int) Position {
+	offset = max(0, min(offset, len(d.text)))
+	line := sort.Search(len(d.lines), func(i int) bool { return d.lines[i] > offset }) - 1
+
+	units := 0
+	for _, r := range d.text[d.lines[line]:offset] {
+		units += utf16.RuneLen(r)
+	}
+	return Position{Line: line, Character: units}
+}
+
+// rangeOf converts a span of byte offsets to a range
+func (d *document) rangeOf(start, end int) Range {
+	return Range{Start: d.position(start), End: d.position(end)}
+}
+
+// lineEnd returns the byte offset of the end of the line containing offset
+func (d *document) lineEnd(offset int) int {
+	line := sort.Search(len(d.lines), func(i int) bool { return d.lines[i] > offset }) - 1
+	if line+1 < len(d.lines) {
+		return d.lines[line+1] - 1
+	}
+	return len(d.text)
+}
+
+// identAt returns the name at the byte offset, including just past its end
+// where the cursor sits after typing it
+func (d *document) identAt(offset int) (yz.Token, bool) {
+	for _, tok := range d.tokens {
+		if tok.Kind == yz.Ident && tok.Pos.Offset <= offset && offset <= tok.End.Offset {
+			return tok, true
+		}
+	}
+	return yz.Token{}, false
+}
@@ -0,0 +1,529 @@
+// Package lsp implements a Yz language server for the playground editor
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"yz-playground/internal/logger"
+	"yz-playground/internal/yz"
+)
+
+// diagnosticSource names the server in diagnostics
+const diagnosticSource = "yz"
+
+// maxMessageLines bounds compiler output shown in a diagnostic without a position
+const maxMessageLines = 20
+
+// Defaults used when Options leaves them unset
+const (
+	defaultDebounce     = 500 * time.Millisecond
+	defaultMaxDocuments = 16
+)
+
+// compilerPosition matches compiler messages that point into a Yz file, e.g. main.yz:3:5: message
+var compilerPosition = regexp.MustCompile(`(?m)^\s*(?:\./)?[\w./-]*\.yz:(\d+)(?::(\d+))?:?\s*(.*)$`)
+
+// builtins describes the functions available to every program
+var builtins = map[string]string{
+	"print":   "print(values) writes its arguments to the output",
+	"println": "println(values) writes its arguments to the output, followed by a newline",
+}
+
+// errExit stops a session when the client sends exit
+var errExit = errors.New("client exited")
+
+// Conn carries JSON-RPC messages, one per call
+type Conn interface {
+	ReadMessage() ([]byte, error)
+	WriteMessage(data []byte) error
+	Close() error
+}
+
+// ErrRateLimited is returned by a CompileFunc that refused to compile because
+// the client made too many requests
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// CompileFunc compiles code without running it. ok is false when the code has
+// compile errors, which output describes; err reports code that couldn't be
+// compiled at all, e.g. because the sandbox is unavailable, or ErrRateLimited.
+type CompileFunc func(ctx context.Context, code string) (output string, ok bool, err error)
+
+// Options configures a language server session
+type Options struct {
+	Compile         CompileFunc   // checks documents with the compiler; nil leaves out compiler diagnostics
+	Debounce        time.Duration // wait after the last change before compiling, default 500ms
+	MaxDocumentSize int           // larger documents aren't compiled; 0 means no limit
+	MaxDocuments    int           // documents a client may keep open, default 16
+	Version         string        // reported to the client
+}
+
+// session is a language server session with one client
+type session struct {
+	ctx  context.Context
+	conn Conn
+	opts Options
+
+	writeMutex sync.Mutex
+	compiling  chan struct{} // holds a token while a compile runs
+
+	mutex       sync.Mutex // guards the fields below
+	initialized bool
+	docs        map[string]*document
+	checks      map[string]*check
+}
+
+// check is a compile of a document that is waiting for edits to settle or running
+type check struct {
+	timer  *time.Timer
+	cancel context.CancelFunc
+}
+
+// Serve runs a language server session over conn until the client exits,
+// the connection fails or ctx is done. Syntax errors are reported as soon as a
+// document changes; compiler diagnostics follow once edits pause for the
+// debounce period, and a newer edit cancels a compile that is still running.
+// A session runs one compile at a time; others wait for it to finish.
+func Serve(ctx context.Context, conn Conn, opts Options) error {
+	if opts.Debounce <= 0 {
+		opts.Debounce = defaultDebounce
+	}
+	if opts.MaxDocuments <= 0 {
+		opts.MaxDocuments = defaultMaxDocuments
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	s := &session{
+		ctx:       ctx,
+		conn:      conn,
+		opts:      opts,
+		compiling: make(chan struct{}, 1),
+		docs:      make(map[string]*document),
+		checks:    make(map[string]*check),
+	}
+	defer s.stopChecks()
+
+	for {
+		data, err := conn.ReadMessage()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		if err := s.receive(data); err != nil {
+			if errors.Is(err, errExit) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// receive handles one message from the client
+func (s *session) receive(data []byte) error {
+	var req request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return s.reply(nil, nil, &responseError{Code: codeParseError, Message: err.Error()})
+	}
+	if req.Method == "" {
+		if req.isNotification() {
+			return nil
+		}
+		return s.reply(req.ID, nil, &responseError{Code: codeInvalidRequest, Message: "missing method"})
+	}
+
+	result, rpcErr := s.handle(&req)
+	if errors.Is(rpcErr, errExit) {
+		return errExit
+	}
+	if req.isNotification() {
+		return nil
+	}
+	var respErr *responseError
+	if rpcErr != nil && !errors.As(rpcErr, &respErr) {
+		respErr = &responseError{Code: codeInvalidParams, Message: rpcErr.Error()}
+	}
+	return s.reply(req.ID, result, respErr)
+}
+
+// Error implements error, so handlers can return JSON-RPC errors
+func (e *responseError) Error() string {
+	return e.Message
+}
+
+// handle dispatches a request or notification and returns its result
+func (s *session) handle(req *request) (any, error) {
+	s.mutex.Lock()
+	initialized := s.initialized
+	s.mutex.Unlock()
+	if !initialized && req.Method != "initialize" && req.Method != "exit" {
+		return nil, &responseError{Code: codeServerNotInitialized, Message: "initialize first"}
+	}
+
+	switch req.Method {
+	case "initialize":
+		s.mutex.Lock()
+		s.initialized = true
+		s.mutex.Unlock()
+		return initializeResult{
+			Capabilities: serverCapabilities{
+				TextDocumentSync:   syncFull,
+				HoverProvider:      true,
+				DefinitionProvider: true,
+				CompletionProvider: completionOptions{TriggerCharacters: []string{"."}},
+			},
+			ServerInfo: serverInfo{Name: "yz-playground", Version: s.opts.Version},
+		}, nil
+	case "initialized", "$/cancelRequest", "$/setTrace":
+		return nil, nil
+	case "shutdown":
+		s.stopChecks()
+		return nil, nil
+	case "exit":
+		return nil, errExit
+
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		s.open(newDocument(params.TextDocument.URI, params.TextDocument.Version, params.TextDocument.Text))
+		return nil, nil
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		if n := len(params.ContentChanges); n > 0 {
+			doc := params.TextDocument
+			s.change(newDocument(doc.URI, doc.Version, params.ContentChanges[n-1].Text))
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		var params didCloseParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		s.close(params.TextDocument.URI)
+		return nil, nil
+
+	case "textDocument/hover":
+		return withDocument(s, req, hover)
+	case "textDocument/definition":
+		return withDocument(s, req, definition)
+	case "textDocument/completion":
+		return withDocument(s, req, completion)
+	}
+
+	if req.isNotification() {
+		return nil, nil
+	}
+	return nil, &responseError{Code: codeMethodNotFound, Message: "method not supported: " + req.Method}
+}
+
+// withDocument decodes the params of a request about a position and calls
+// answer with the document's current snapshot
+func withDocument[T any](s *session, req *request, answer func(*document, Position) T) (any, error) {
+	var params positionParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return nil, err
+	}
+	s.mutex.Lock()
+	doc := s.docs[params.TextDocument.URI]
+	s.mutex.Unlock()
+	if doc == nil {
+		return nil, nil
+	}
+	return answer(doc, params.Position), nil
+}
+
+// open starts tracking a document
+func (s *session) open(doc *document) {
+	s.mutex.Lock()
+	if _, ok := s.docs[doc.uri]; !ok && len(s.docs) >= s.opts.MaxDocuments {
+		s.mutex.Unlock()
+		logger.FromContext(s.ctx).Warn("Too many open documents, ignoring", "uri", doc.uri, "limit", s.opts.MaxDocuments)
+		return
+	}
+	s.docs[doc.uri] = doc
+	s.mutex.Unlock()
+	s.diagnose(doc)
+}
+
+// change replaces a tracked document with a newer version
+func (s *session) change(doc *document) {
+	s.mutex.Lock()
+	_, ok := s.docs[doc.uri]
+	if ok {
+		s.docs[doc.uri] = doc
+	}
+	s.mutex.Unlock()
+	if ok {
+		s.diagnose(doc)
+	}
+}
+
+// close stops tracking a document and clears its diagnostics
+func (s *session) close(uri string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.stopCheck(uri)
+	if doc, ok := s.docs[uri]; ok {
+		delete(s.docs, uri)
+		s.publish(doc, nil)
+	}
+}
+
+// diagnose reports syntax errors in doc right away, otherwise schedules a
+// compile once edits pause, replacing any compile still pending or running.
+// Diagnostics are published with s.mutex held, so a compile that finishes
+// late can't overwrite those of a newer version.
+func (s *session) diagnose(doc *document) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.stopCheck(doc.uri)
+
+	switch {
+	case doc.syntaxErr != nil:
+		pos := doc.syntaxErr.Pos.Offset
+		end := pos + 1
+		if pos >= len(doc.text) || doc.text[pos] == '\n' {
+			end = pos
+		}
+		s.publish(doc, []Diagnostic{{
+			Range:    doc.rangeOf(pos, end),
+			Severity: SeverityError,
+			Source:   diagnosticSource,
+			Message:  doc.syntaxErr.Msg,
+		}})
+	case s.opts.Compile == nil:
+		s.publish(doc, nil)
+	case s.opts.MaxDocumentSize > 0 && len(doc.text) > s.opts.MaxDocumentSize:
+		s.publish(doc, []Diagnostic{{
+			Range:    doc.rangeOf(0, 0),
+			Severity: SeverityWarning,
+			Source:   diagnosticSource,
+			Message:  fmt.Sprintf("Not compiled: the code is larger than %d bytes", s.opts.MaxDocumentSize),
+		}})
+	default:
+		ctx, cancel := context.WithCancel(s.ctx)
+		s.checks[doc.uri] = &check{
+			timer:  time.AfterFunc(s.opts.Debounce, func() { s.compile(ctx, doc) }),
+			cancel: cancel,
+		}
+	}
+}
+
+// stopCheck cancels the compile of a document, if any; the caller holds s.mutex
+func (s *session) stopCheck(uri string) {
+	if c, ok := s.checks[uri]; ok {
+		c.timer.Stop()
+		c.cancel()
+		delete(s.checks, uri)
+	}
+}
+
+// stopChecks cancels every compile
+func (s *session) stopChecks() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for uri := range s.checks {
+		s.stopCheck(uri)
+	}
+}
+
+// compile checks doc with the compiler and publishes its diagnostics, unless
+// the document changed in the meantime
+func (s *session) compile(ctx context.Context, doc *document) {
+	select {
+	case s.compiling <- struct{}{}:
+		defer func() { <-s.compiling }()
+	case <-ctx.Done():
+		return
+	}
+
+	output, ok, err := s.opts.Compile(ctx, doc.text)
+	if ctx.Err() != nil {
+		return
+	}
+	if errors.Is(err, ErrRateLimited) {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		if s.docs[doc.uri] == doc {
+			s.publish(doc, []Diagnostic{{
+				Range:    doc.rangeOf(0, 0),
+				Severity: SeverityWarning,
+				Source:   diagnosticSource,
+				Message:  "Not compiled: rate limit exceeded; the code is compiled again after the next change",
+			}})
+		}
+		return
+	}
+	if err != nil {
+		logger.FromContext(ctx).Warn("Language server compile failed", "uri", doc.uri, "error", err)
+		return
+	}
+
+	var diagnostics []Diagnostic
+	if !ok {
+		diagnostics = compileDiagnostics(doc, output)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.docs[doc.uri] == doc {
+		s.publish(doc, diagnostics)
+	}
+}
+
+// compileDiagnostics turns compiler output into diagnostics, placed where the
+// compiler points or at the start of the document if it doesn't
+func compileDiagnostics(doc *document, output string) []Diagnostic {
+	var diagnostics []Diagnostic
+	for _, match := range compilerPosition.FindAllStringSubmatch(output, -1) {
+		line, _ := strconv.Atoi(match[1])
+		column, _ := strconv.Atoi(match[2])
+		if line < 1 || line > len(doc.lines) {
+			line = 1
+		}
+		start := min(doc.lines[line-1]+max(column-1, 0), doc.lineEnd(doc.lines[line-1]))
+		end := doc.lineEnd(start)
+		if tok, ok := doc.identAt(start); ok && tok.Pos.Offset == start {
+			end = tok.End.Offset
+		}
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    doc.rangeOf(start, end),
+			Severity: SeverityError,
+			Source:   diagnosticSource,
+			Message:  strings.TrimSpace(match[3]),
+		})
+	}
+	if len(diagnostics) > 0 {
+		return diagnostics
+	}
+
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) > maxMessageLines {
+		lines = append(lines[:maxMessageLines], "...")
+	}
+	message := strings.Join(lines, "\n")
+	if message == "" {
+		message = "Compilation failed"
+	}
+	return []Diagnostic{{
+		Range:    doc.rangeOf(0, doc.lineEnd(0)),
+		Severity: SeverityError,
+		Source:   diagnosticSource,
+		Message:  message,
+	}}
+}
+
+// hover describes the name under the cursor
+func hover(doc *document, pos Position) *Hover {
+	offset := doc.offset(pos)
+	tok, ok := doc.identAt(offset)
+	if !ok {
+		return nil
+	}
+
+	var value string
+	if sym := yz.Lookup(doc.symbols, tok.Text, offset); sym != nil {
+		value = "```yz\n" + sym.Detail + "\n```"
+	} else if description, ok := builtins[tok.Text]; ok {
+		value = description
+	} else {
+		return nil
+	}
+	r := doc.rangeOf(tok.Pos.Offset, tok.End.Offset)
+	return &Hover{Contents: MarkupContent{Kind: "markdown", Value: value}, Range: &r}
+}
+
+// definition finds where the name under the cursor is declared
+func definition(doc *document, pos Position) *Location {
+	offset := doc.offset(pos)
+	tok, ok := doc.identAt(offset)
+	if !ok {
+		return nil
+	}
+	sym := yz.Lookup(doc.symbols, tok.Text, offset)
+	if sym == nil {
+		return nil
+	}
+	return &Location{
+		URI:   doc.uri,
+		Range: doc.rangeOf(sym.Pos.Offset, sym.Pos.Offset+len(sym.Name)),
+	}
+}
+
+// completion suggests the names visible at the cursor and the builtins
+func completion(doc *document, pos Position) completionList {
+	offset := doc.offset(pos)
+	var items []CompletionItem
+	for _, sym := range yz.Visible(doc.symbols, offset) {
+		kind := CompletionVariable
+		if sym.Kind == yz.SymbolBlock {
+			kind = CompletionFunction
+		}
+		items = append(items, CompletionItem{Label: sym.Name, Kind: kind, Detail: sym.Detail})
+	}
+	for name, description := range builtins {
+		items = append(items, CompletionItem{Label: name, Kind: CompletionFunction, Detail: description})
+	}
+	return completionList{Items: items}
+}
+
+// publish replaces the diagnostics of a document; the caller holds s.mutex
+func (s *session) publish(doc *document, diagnostics []Diagnostic) {
+	if diagnostics == nil {
+		diagnostics = []Diagnostic{}
+	}
+	version := doc.version
+	err := s.write(notification{
+		JSONRPC: "2.0",
+		Method:  "textDocument/publishDiagnostics",
+		Params:  publishDiagnosticsParams{URI: doc.uri, Version: &version, Diagnostics: diagnostics},
+	})
+	if err != nil {
+		logger.FromContext(s.ctx).Debug("Failed to publish diagnostics", "uri", doc.uri, "error", err)
+	}
+}
+
+// reply answers a request
+func (s *session) reply(id json.RawMessage, result any, rpcErr *responseError) error {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	resp := response{JSONRPC: "2.0", ID: id, Error: rpcErr}
+	if rpcErr == nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		raw := json.RawMessage(data)
+		resp.Result = &raw
+	}
+	return s.write(resp)
+}
+
+// write sends a message to the client
+func (s *session) write(message any) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+	return s.conn.WriteMessage(data)
+}
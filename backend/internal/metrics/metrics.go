@@ -0,0 +1,255 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"yz-playground/internal/sandbox"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes every metric name
+const namespace = "yz_playground"
+
+// containerCheckInterval is how long the sandbox container gauge reuses a check
+const containerCheckInterval = 15 * time.Second
+
+// containerCheckTimeout bounds a sandbox container check
+const containerCheckTimeout = 2 * time.Second
+
+// Outcomes of executions that failed before producing a result
+const (
+	outcomeSandboxError = "sandbox_error"
+	outcomeQueueFull    = "queue_full"
+	outcomeShuttingDown = "shutting_down"
+)
+
+// Metrics holds the Prometheus collectors for the playground
+type Metrics struct {
+	registry *prometheus.Registry
+
+	executions      *prometheus.CounterVec
+	compileDuration prometheus.Histogram
+	runDuration     prometheus.Histogram
+	queueWait       prometheus.Histogram
+	memoryUsed      prometheus.Histogram
+	cacheLookups    *prometheus.CounterVec
+	httpRequests    *prometheus.CounterVec
+	httpDuration    *prometheus.HistogramVec
+}
+
+// New creates and registers the playground metrics
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		executions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "executions_total",
+			Help:      "Code executions by outcome.",
+		}, []string{"outcome"}),
+		compileDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "compile_duration_seconds",
+			Help:      "Time spent compiling Yz code.",
+			Buckets:   []float64{.25, .5, 1, 2, 4, 8, 16, 32},
+		}),
+		runDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "run_duration_seconds",
+			Help:      "Time spent running compiled programs.",
+			Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		}),
+		queueWait: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "queue_wait_seconds",
+			Help:      "Time executions waited for a free sandbox slot.",
+			Buckets:   []float64{.001, .01, .1, .5, 1, 2.5, 5, 10, 30},
+		}),
+		memoryUsed: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "memory_used_bytes",
+			Help:      "Peak resident memory of executions.",
+			Buckets:   prometheus.ExponentialBuckets(8<<20, 2, 8), // 8MB to 1GB
+		}),
+		cacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_lookups_total",
+			Help:      "Cache lookups by cache and result.",
+		}, []string{"cache", "result"}),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by route, method and status.",
+		}, []string{"route", "method", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+	}
+
+	m.registry.MustRegister(
+		m.executions,
+		m.compileDuration,
+		m.runDuration,
+		m.queueWait,
+		m.memoryUsed,
+		m.cacheLookups,
+		m.httpRequests,
+		m.httpDuration,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+// RegisterSandboxGauges exposes live sandbox state from the manager. The
+// sandbox container is checked in the background at most every
+// containerCheckInterval, so scrapes never wait on Docker.
+func (m *Metrics) RegisterSandboxGauges(manager *sandbox.Manager) {
+	container := &containerGauge{check: manager.CheckContainer}
+	container.start()
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "sandbox_container_up",
+		Help:      "Whether the sandbox container was running when last checked.",
+	}, container.value))
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_sandboxes",
+		Help:      "Executions running in the sandbox, one per busy slot.",
+	}, func() float64 {
+		return float64(manager.RunningExecutions())
+	}))
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "queued_executions",
+		Help:      "Executions waiting for a free sandbox slot.",
+	}, func() float64 {
+		return float64(manager.QueuedExecutions())
+	}))
+}
+
+// containerGauge reports the sandbox container's state from its last check
+type containerGauge struct {
+	check     func(context.Context) error
+	mutex     sync.Mutex
+	up        bool
+	checkedAt time.Time
+	checking  bool
+}
+
+// value returns 1 if the container was up when last checked, and starts a
+// new check if that one is stale
+func (g *containerGauge) value() float64 {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if time.Since(g.checkedAt) >= containerCheckInterval {
+		g.startLocked()
+	}
+	if g.up {
+		return 1
+	}
+	return 0
+}
+
+// start begins a check in the background unless one is running
+func (g *containerGauge) start() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.startLocked()
+}
+
+// startLocked is start with the mutex held
+func (g *containerGauge) startLocked() {
+	if g.checking {
+		return
+	}
+	g.checking = true
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), containerCheckTimeout)
+		defer cancel()
+		err := g.check(ctx)
+
+		g.mutex.Lock()
+		defer g.mutex.Unlock()
+		g.up = err == nil
+		g.checkedAt = time.Now()
+		g.checking = false
+	}()
+}
+
+// ExecutionFinished records a completed execution
+func (m *Metrics) ExecutionFinished(result *sandbox.ExecutionResult) {
+	m.executions.WithLabelValues(result.Outcome).Inc()
+
+	m.compileDuration.Observe(milliseconds(result.CompileTime))
+	if result.RunTime > 0 {
+		m.runDuration.Observe(milliseconds(result.RunTime))
+	}
+	m.queueWait.Observe(milliseconds(result.QueueTime))
+	if result.MemoryUsed > 0 {
+		m.memoryUsed.Observe(float64(result.MemoryUsed))
+	}
+}
+
+// ExecutionFailed records an execution the sandbox couldn't carry out
+func (m *Metrics) ExecutionFailed(err error) {
+	switch {
+	case errors.Is(err, sandbox.ErrQueueFull):
+		m.executions.WithLabelValues(outcomeQueueFull).Inc()
+	case errors.Is(err, sandbox.ErrShuttingDown):
+		m.executions.WithLabelValues(outcomeShuttingDown).Inc()
+	case errors.Is(err, context.Canceled):
+		// The client went away while the execution waited for a slot
+		m.executions.WithLabelValues(sandbox.OutcomeCanceled).Inc()
+	default:
+		m.executions.WithLabelValues(outcomeSandboxError).Inc()
+	}
+}
+
+// CacheLookup records a cache hit or miss
+func (m *Metrics) CacheLookup(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.cacheLookups.WithLabelValues(cache, result).Inc()
+}
+
+// Handler returns the HTTP handler serving the metrics
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Middleware records request counts and latency per route
+func (m *Metrics) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		// Use the route pattern rather than the raw path to keep label cardinality bounded
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		m.httpRequests.WithLabelValues(route, c.Request.Method, strconv.Itoa(c.Writer.Status())).Inc()
+		m.httpDuration.WithLabelValues(route, c.Request.Method).Observe(time.Since(start).Seconds())
+	}
+}
+
+// milliseconds converts a millisecond count to seconds
+func milliseconds(ms int) float64 {
+	return float64(ms) / 1000
+}
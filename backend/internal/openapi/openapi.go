@@ -0,0 +1,261 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Version is the OpenAPI version of generated documents
+const Version = "3.0.3"
+
+// Security scheme names used by secured operations
+const (
+	schemeAPIKey = "ApiKeyAuth"
+	schemeBearer = "BearerAuth"
+)
+
+// Document builds an OpenAPI document whose schemas are generated from the
+// Go types used as request and response bodies, so it can't drift from them
+type Document struct {
+	title      string
+	version    string
+	serverURL  string
+	keyHeader  string
+	paths      map[string]map[string]any
+	schemas    map[string]any
+	enums      map[reflect.Type][]string
+	hasSecured bool
+}
+
+// Operation describes one endpoint
+type Operation struct {
+	Summary            string
+	Description        string
+	Request            any    // request body value, nil if there is none
+	RequestContentType string // defaults to application/json
+	RequestSchema      any    // overrides the schema generated from Request, for non-JSON content
+	Responses          []Response
+	Secured            bool // whether the operation takes an API key
+}
+
+// Response describes one response of an operation
+type Response struct {
+	Status      int
+	Description string
+	Body        any    // response body value, nil if there is none
+	ContentType string // defaults to application/json
+	Schema      any    // overrides the schema generated from Body, for non-JSON content
+}
+
+// New creates an empty document for an API served under serverURL.
+// Secured operations accept an API key in keyHeader or as a bearer token.
+func New(title, version, serverURL, keyHeader string) *Document {
+	return &Document{
+		title:     title,
+		version:   version,
+		serverURL: serverURL,
+		keyHeader: keyHeader,
+		paths:     make(map[string]map[string]any),
+		schemas:   make(map[string]any),
+		enums:     make(map[reflect.Type][]string),
+	}
+}
+
+// Enum documents the allowed values of a string type
+func Enum[T ~string](d *Document, values ...T) {
+	names := make([]string, len(values))
+	for i, value := range values {
+		names[i] = string(value)
+	}
+	d.enums[reflect.TypeFor[T]()] = names
+}
+
+// Add documents an operation; path is relative to the server URL and may
+// have gin parameters such as :id
+func (d *Document) Add(method, path string, op Operation) {
+	operation := map[string]any{
+		"summary":     op.Summary,
+		"operationId": operationID(method, path),
+	}
+	if op.Description != "" {
+		operation["description"] = op.Description
+	}
+	path, params := pathParams(path)
+	if len(params) > 0 {
+		parameters := make([]map[string]any, 0, len(params))
+		for _, name := range params {
+			parameters = append(parameters, map[string]any{
+				"name":     name,
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]any{"type": "string"},
+			})
+		}
+		operation["parameters"] = parameters
+	}
+	if op.Request != nil || op.RequestSchema != nil {
+		contentType := op.RequestContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		schema := op.RequestSchema
+		if schema == nil {
+			schema = d.schema(reflect.TypeOf(op.Request))
+		}
+		operation["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{contentType: map[string]any{"schema": schema}},
+		}
+	}
+	if op.Secured {
+		// The empty requirement keeps the key optional unless the server requires one
+		operation["security"] = []map[string][]string{{schemeAPIKey: {}}, {schemeBearer: {}}, {}}
+		d.hasSecured = true
+	}
+
+	responses := make(map[string]any)
+	for _, resp := range op.Responses {
+		description := resp.Description
+		if description == "" {
+			description = http.StatusText(resp.Status)
+		}
+		response := map[string]any{"description": description}
+		if resp.Body != nil || resp.Schema != nil {
+			contentType := resp.ContentType
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			schema := resp.Schema
+			if schema == nil {
+				schema = d.schema(reflect.TypeOf(resp.Body))
+			}
+			response["content"] = map[string]any{contentType: map[string]any{"schema": schema}}
+		}
+		responses[strconv.Itoa(resp.Status)] = response
+	}
+	operation["responses"] = responses
+
+	if d.paths[path] == nil {
+		d.paths[path] = make(map[string]any)
+	}
+	d.paths[path][strings.ToLower(method)] = operation
+}
+
+// MarshalJSON implements json.Marshaler
+func (d *Document) MarshalJSON() ([]byte, error) {
+	components := map[string]any{"schemas": d.schemas}
+	if d.hasSecured {
+		components["securitySchemes"] = map[string]any{
+			schemeAPIKey: map[string]any{"type": "apiKey", "in": "header", "name": d.keyHeader},
+			schemeBearer: map[string]any{"type": "http", "scheme": "bearer"},
+		}
+	}
+	return json.Marshal(map[string]any{
+		"openapi":    Version,
+		"info":       map[string]any{"title": d.title, "version": d.version},
+		"servers":    []map[string]any{{"url": d.serverURL}},
+		"paths":      d.paths,
+		"components": components,
+	})
+}
+
+// schema returns the schema for t, registering named structs as components
+func (d *Document) schema(t reflect.Type) map[string]any {
+	if values, ok := d.enums[t]; ok {
+		return map[string]any{"type": "string", "enum": values}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return d.schema(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int32, reflect.Uint, reflect.Uint32:
+		return map[string]any{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			// encoding/json writes byte slices as base64
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": d.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": d.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.structSchema(t)
+		}
+		if _, ok := d.schemas[t.Name()]; !ok {
+			// Reserve the name first so self-referencing types terminate
+			d.schemas[t.Name()] = nil
+			d.schemas[t.Name()] = d.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	}
+	// Interfaces and anything else accept any value
+	return map[string]any{}
+}
+
+// structSchema builds an object schema from a struct's JSON fields.
+// Fields without omitempty are always present, so they are required.
+func (d *Document) structSchema(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = d.schema(field.Type)
+		if !strings.Contains(options, "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// operationID derives a stable operation ID such as postExecuteStream from a method and path
+func operationID(method, path string) string {
+	id := strings.ToLower(method)
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '.' || r == '_' }) {
+		if name, ok := strings.CutPrefix(part, ":"); ok {
+			part = "by" + strings.ToUpper(name[:1]) + name[1:]
+		}
+		id += strings.ToUpper(part[:1]) + part[1:]
+	}
+	return id
+}
+
+// pathParams rewrites the gin parameters of path, such as :id, in OpenAPI
+// form, such as {id}, and returns their names
+func pathParams(path string) (string, []string) {
+	var params []string
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			params = append(params, name)
+			segments[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
@@ -0,0 +1,248 @@
+// Package profiling summarizes the pprof profiles of programs compiled from
+// Yz. Functions of the generated program are named after the Yz declarations
+// they come from where the Go names allow it.
+package profiling
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"yz-playground/internal/yz"
+
+	"github.com/google/pprof/profile"
+)
+
+// Sample types tables are made of
+const (
+	SampleCPU        = "cpu"         // CPU time, in CPU profiles
+	SampleAllocSpace = "alloc_space" // bytes allocated, in heap profiles
+)
+
+// programMain is what the profiling harness renames the generated main to.
+// The harness's own main and the profiler's work aren't part of the program.
+const programMain = "yzMain"
+
+// maxUncompressed bounds the bytes a profile may decompress to, so a small
+// gzip-compressed profile can't inflate to gigabytes in the backend
+const maxUncompressed = 64 << 20
+
+// ErrTooLarge is returned for a profile that decompresses to more than
+// maxUncompressed bytes
+var ErrTooLarge = errors.New("profile is larger than allowed once decompressed")
+
+// profilerPackage is the package of the profiler, whose samples are left out
+const profilerPackage = "runtime/pprof."
+
+// Function is a row of a table: the samples spent in one function
+type Function struct {
+	Name   string // the Yz name if Yz is set, otherwise the Go name
+	GoName string
+	Yz     bool   // whether the function comes from a Yz declaration
+	File   string // where the function is declared, if known
+	Line   int
+	Flat   int64 // spent in the function itself
+	Cum    int64 // spent in the function and what it calls
+}
+
+// Table lists the functions a profile's samples were spent in
+type Table struct {
+	SampleType string
+	Unit       string // e.g. nanoseconds or bytes
+	Total      int64
+	Functions  []Function // by decreasing Flat, then Cum
+}
+
+// Names maps Yz names, normalized by normalize, to the names declared in the
+// sources of a program
+type Names map[string]string
+
+// NewNames collects the names declared in the Yz sources of a program.
+// Sources that can't be parsed are left out.
+func NewNames(sources ...string) Names {
+	names := Names{normalize("main"): "main"}
+	for _, src := range sources {
+		parsed, err := yz.Parse(src)
+		if err != nil {
+			continue
+		}
+		for _, sym := range yz.Symbols(parsed.Tokens) {
+			if _, ok := names[normalize(sym.Name)]; !ok {
+				names[normalize(sym.Name)] = sym.Name
+			}
+		}
+	}
+	return names
+}
+
+// normalize lowercases name and drops its underscores, so that names the Go
+// code generator changed to follow Go conventions still match
+func normalize(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", ""))
+}
+
+// generatedPart matches the parts of Go function names the compiler adds for
+// closures, go and defer statements, and nested closures
+var generatedPart = regexp.MustCompile(`^(func|gowrap|deferwrap)?\d+$`)
+
+// yzName returns the Yz name of a Go function, and whether it has one.
+// Functions of package main whose every part names a Yz declaration have
+// one; closures count as the function they are in.
+func (n Names) yzName(goName string) (string, bool) {
+	rest, ok := strings.CutPrefix(goName, "main.")
+	if !ok {
+		return "", false
+	}
+	var parts []string
+	for _, part := range strings.Split(rest, ".") {
+		if generatedPart.MatchString(part) {
+			break
+		}
+		part = strings.Trim(part, "(*)")
+		switch part {
+		case "main":
+			return "", false
+		case programMain:
+			part = "main"
+		}
+		name, ok := n[normalize(part)]
+		if !ok {
+			return "", false
+		}
+		parts = append(parts, name)
+	}
+	if len(parts) == 0 {
+		return "", false
+	}
+	return strings.Join(parts, "."), true
+}
+
+// Parse parses a pprof profile, gzip-compressed or not
+func Parse(data []byte) (*profile.Profile, error) {
+	if isGzip(data) {
+		gr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid profile: %w", err)
+		}
+		defer gr.Close()
+		data, err = io.ReadAll(io.LimitReader(gr, maxUncompressed+1))
+		if err != nil {
+			return nil, fmt.Errorf("invalid profile: %w", err)
+		}
+		if len(data) > maxUncompressed {
+			return nil, ErrTooLarge
+		}
+		// profile.ParseData would decompress a nested gzip stream without a limit
+		if isGzip(data) {
+			return nil, errors.New("invalid profile: compressed more than once")
+		}
+	}
+	p, err := profile.ParseData(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid profile: %w", err)
+	}
+	return p, nil
+}
+
+// isGzip reports whether data starts like a gzip stream
+func isGzip(data []byte) bool {
+	return bytes.HasPrefix(data, []byte{0x1f, 0x8b})
+}
+
+// Top returns a table of the n functions with the most samples of
+// sampleType in p, naming them with names
+func Top(p *profile.Profile, sampleType string, n int, names Names) (*Table, error) {
+	index := -1
+	for i, st := range p.SampleType {
+		if st.Type == sampleType {
+			index = i
+		}
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("profile has no %s samples", sampleType)
+	}
+
+	t := &Table{SampleType: sampleType, Unit: p.SampleType[index].Unit}
+	functions := make(map[string]*Function)
+	for _, sample := range p.Sample {
+		value := sample.Value[index]
+		if value == 0 || profilerSample(sample) {
+			continue
+		}
+		t.Total += value
+
+		// Count each function once per sample, even when it recurses
+		seen := make(map[*Function]bool)
+		for i, loc := range sample.Location {
+			for j, line := range loc.Line {
+				if line.Function == nil {
+					continue
+				}
+				f := names.function(functions, line.Function)
+				if !seen[f] {
+					f.Cum += value
+					seen[f] = true
+				}
+				// The first line of the first location is where the sample was taken
+				if i == 0 && j == 0 {
+					f.Flat += value
+				}
+			}
+		}
+	}
+
+	for _, f := range functions {
+		t.Functions = append(t.Functions, *f)
+	}
+	sort.Slice(t.Functions, func(i, j int) bool {
+		a, b := t.Functions[i], t.Functions[j]
+		if a.Flat != b.Flat {
+			return a.Flat > b.Flat
+		}
+		if a.Cum != b.Cum {
+			return a.Cum > b.Cum
+		}
+		return a.Name < b.Name
+	})
+	if len(t.Functions) > n {
+		t.Functions = t.Functions[:n]
+	}
+	return t, nil
+}
+
+// profilerSample reports whether a sample was taken in the profiler
+func profilerSample(sample *profile.Sample) bool {
+	for _, loc := range sample.Location {
+		for _, line := range loc.Line {
+			if line.Function != nil && strings.HasPrefix(line.Function.Name, profilerPackage) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// function returns the row of functions for fn, adding it if needed. Rows are
+// keyed by Yz name where there is one, so closures add to the declaration
+// they are in.
+func (n Names) function(functions map[string]*Function, fn *profile.Function) *Function {
+	name, isYz := n.yzName(fn.Name)
+	if !isYz {
+		name = fn.Name
+	}
+	if f, ok := functions[name]; ok {
+		// Prefer the declaration's own Go function to its closures
+		if len(fn.Name) < len(f.GoName) {
+			f.GoName, f.File, f.Line = fn.Name, fn.Filename, int(fn.StartLine)
+		}
+		return f
+	}
+	f := &Function{Name: name, GoName: fn.Name, Yz: isYz, File: fn.Filename, Line: int(fn.StartLine)}
+	functions[name] = f
+	return f
+}
@@ -0,0 +1,77 @@
+package sandbox
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// benchmarkPrefix tags the line the benchmark script prints after each run:
+// wall time in microseconds, then user and system CPU seconds and peak
+// memory in KB when GNU time is available
+const benchmarkPrefix = "__YZ_BENCH__ "
+
+// benchmarkScript builds the program once and runs it $2 times, stopping at
+// the first run that fails. Only the first run's output and errors are kept;
+// later runs print both to /dev/null so they don't count against the output
+// limit.
+const benchmarkScript = buildScript + startScript + `stats=$(mktemp); trap 'rm -f "$stats"' EXIT
+exec 3>&1
+for ((i = 1; i <= $2; i++)); do
+  : > "$stats"
+  start=${EPOCHREALTIME/[.,]/}
+  if [ -x /usr/bin/time ]; then /usr/bin/time -o "$stats" -f '%U %S %M' "$app" >&3 2>&3; else "$app" >&3 2>&3; fi
+  status=$?
+  end=${EPOCHREALTIME/[.,]/}
+  [ $status -eq 0 ] || exit $status
+  echo "` + benchmarkPrefix + `$((end - start)) $(tail -n 1 "$stats")"
+  exec 3>/dev/null
+done`
+
+// RunStats holds the measurements of one run of a benchmark
+type RunStats struct {
+	WallTime   time.Duration
+	CPUTime    time.Duration // user and system time, 0 if unknown
+	MemoryUsed int64         // peak resident memory in bytes, 0 if unknown
+}
+
+// extractBenchmarkRuns removes the benchmark script's lines from output and
+// returns the runs they describe
+func extractBenchmarkRuns(output string) (string, []RunStats) {
+	var runs []RunStats
+	lines := strings.Split(output, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		fields, ok := strings.CutPrefix(strings.TrimSpace(line), strings.TrimSpace(benchmarkPrefix))
+		if !ok {
+			kept = append(kept, line)
+			continue
+		}
+		if run, ok := parseRunStats(strings.Fields(fields)); ok {
+			runs = append(runs, run)
+		}
+	}
+	return strings.Join(kept, "\n"), runs
+}
+
+// parseRunStats parses the fields of a benchmark line
+func parseRunStats(fields []string) (RunStats, bool) {
+	if len(fields) == 0 {
+		return RunStats{}, false
+	}
+	wall, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return RunStats{}, false
+	}
+	run := RunStats{WallTime: time.Duration(wall) * time.Microsecond}
+	if len(fields) == 4 {
+		user, userErr := strconv.ParseFloat(fields[1], 64)
+		system, systemErr := strconv.ParseFloat(fields[2], 64)
+		kb, memoryErr := strconv.ParseInt(fields[3], 10, 64)
+		if userErr == nil && systemErr == nil && memoryErr == nil {
+			run.CPUTime = time.Duration((user + system) * float64(time.Second))
+			run.MemoryUsed = kb * 1024
+		}
+	}
+	return run, true
+}
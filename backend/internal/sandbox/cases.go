@@ -0,0 +1,173 @@
+package sandbox
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// casesDir is the directory in the container a judged run's case inputs are
+// copied to, followed by the name of the run's project directory. It and the
+// inputs belong to root and only root can read them, so the program can't
+// read a hidden case's input and print it while running on a sample.
+const casesDir = "/tmp/yz-cases-"
+
+// dropPrivileges runs a command as the sandbox user from a script running as root
+const dropPrivileges = "setpriv --reuid=yzuser --regid=yzuser --init-groups"
+
+// asUser runs a command as the sandbox user with its own home directory
+const asUser = dropPrivileges + " env HOME=/home/yzuser"
+
+// casePrefix tags the line the cases script prints after each case: its
+// number, exit status, wall time in microseconds, peak memory in KB or "-"
+// when GNU time isn't available, its base64 output or "-" if it printed
+// nothing, and 1 if the kernel's OOM killer killed a process in the container
+// while it ran or 0 if not
+const casePrefix = "__YZ_CASE__ "
+
+// timeoutStatus is the exit status of timeout when it stopped the command
+const timeoutStatus = 124
+
+// casesScript builds the program once and runs it on each of the $4 case
+// inputs in directory $5 in turn, with a time limit of $2 seconds and at most
+// $3 bytes of output kept. The script runs as root so it can read the inputs;
+// the build and the program run as the sandbox user, the program with only
+// its own case's input on standard input. Once built, the project directory
+// is handed to root and made read-only, and the program is copied to a
+// directory of root's, so no case can change what the next one runs. Each
+// case runs in a fresh scratch directory, which is also its home and temporary
+// directory, removed when the case is done. Each run's output goes to a file
+// rather than the output recorder; standard error is discarded. Every case
+// runs, whatever the previous ones did.
+const casesScript = `as_user="` + asUser + `"
+` + oomKillsFunc + buildScript + `chown -R root:root . && chmod -R a-w . || exit 1
+bin=$(mktemp -d) && chmod 755 "$bin" && install -m 755 "$app" "$bin/app" || exit 1
+app=$bin/app
+` + startScript + `stats=$(mktemp); out=$(mktemp); scratch=; trap 'cd /; rm -rf "$stats" "$out" "$bin" "$scratch"' EXIT
+for ((i = 1; i <= $4; i++)); do
+  : > "$stats"
+  scratch=$(mktemp -d) && chown yzuser:yzuser "$scratch" && cd "$scratch" || exit 1
+  run_as="` + dropPrivileges + ` env HOME=$scratch TMPDIR=$scratch"
+  kills=$(oom_kills); start=${EPOCHREALTIME/[.,]/}
+  if [ -x /usr/bin/time ]; then
+    timeout -k 1 "$2" /usr/bin/time -o "$stats" -f '%M' $run_as "$app" < "$5/$i.in" 2>/dev/null | head -c $(($3 + 1)) > "$out"
+  else
+    timeout -k 1 "$2" $run_as "$app" < "$5/$i.in" 2>/dev/null | head -c $(($3 + 1)) > "$out"
+  fi
+  status=${PIPESTATUS[0]}
+  end=${EPOCHREALTIME/[.,]/}
+  oom=0; [ "$(oom_kills)" = "$kills" ] || oom=1
+  cd / && rm -rf "$scratch"
+  kb=$(tail -n 1 "$stats"); output=$(base64 -w 0 "$out")
+  echo "` + casePrefix + `$i $status $((end - start)) ${kb:--} ${output:--} $oom"
+done
+exit 0`
+
+// CaseRun is the outcome of running the program on one case's input
+type CaseRun struct {
+	Outcome    string // OutcomeSuccess, OutcomeRuntimeError, OutcomeTimeout, OutcomeOOM or OutcomeOutputLimit
+	ExitStatus int
+	Output     string // standard output, up to the case output limit
+	WallTime   time.Duration
+	MemoryUsed int64 // peak resident memory in bytes, 0 if unknown
+}
+
+// caseArchive returns a tar archive of directory dir holding the input of
+// each case, readable by root alone
+func caseArchive(dir string, cases []string) (*bytes.Buffer, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+
+	if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: dir + "/", Mode: 0700}); err != nil {
+		return nil, fmt.Errorf("failed to write tar header: %w", err)
+	}
+	for i, input := range cases {
+		header := &tar.Header{Typeflag: tar.TypeReg, Name: dir + "/" + strconv.Itoa(i+1) + ".in", Size: int64(len(input)), Mode: 0600}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, fmt.Errorf("failed to write input of case %d: %w", i+1, err)
+		}
+		if _, err := tw.Write([]byte(input)); err != nil {
+			return nil, fmt.Errorf("failed to write input of case %d: %w", i+1, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close tar writer: %w", err)
+	}
+	return &buf, nil
+}
+
+// extractCaseRuns removes the cases script's lines from output and returns
+// the runs of the first of count cases they describe, classified with the
+// limits they ran under. Runs are placed by their case number; lines with a
+// number out of range are ignored, and a case reported more than once counts
+// as not run, as do the cases after it.
+func extractCaseRuns(output string, count int, timeout time.Duration, maxOutput int) (string, []CaseRun) {
+	runs := make([]CaseRun, count)
+	reports := make([]int, count)
+	lines := strings.Split(output, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		fields, ok := strings.CutPrefix(strings.TrimSpace(line), strings.TrimSpace(casePrefix))
+		if !ok {
+			kept = append(kept, line)
+			continue
+		}
+		n, run, ok := parseCaseRun(strings.Fields(fields), timeout, maxOutput)
+		if !ok || n < 1 || n > count {
+			continue
+		}
+		runs[n-1] = run
+		reports[n-1]++
+	}
+
+	ran := 0
+	for ran < count && reports[ran] == 1 {
+		ran++
+	}
+	return strings.Join(kept, "\n"), runs[:ran]
+}
+
+// parseCaseRun parses the fields of a case line into its case number and run
+func parseCaseRun(fields []string, timeout time.Duration, maxOutput int) (int, CaseRun, bool) {
+	if len(fields) != 6 {
+		return 0, CaseRun{}, false
+	}
+	n, nErr := strconv.Atoi(fields[0])
+	status, statusErr := strconv.Atoi(fields[1])
+	wall, wallErr := strconv.ParseInt(fields[2], 10, 64)
+	if nErr != nil || statusErr != nil || wallErr != nil {
+		return 0, CaseRun{}, false
+	}
+	run := CaseRun{ExitStatus: status, WallTime: time.Duration(wall) * time.Microsecond}
+	if kb, err := strconv.ParseInt(fields[3], 10, 64); err == nil {
+		run.MemoryUsed = kb * 1024
+	}
+	if fields[4] != "-" {
+		output, err := base64.StdEncoding.DecodeString(fields[4])
+		if err != nil {
+			return 0, CaseRun{}, false
+		}
+		run.Output = string(output)
+	}
+
+	switch {
+	case status == timeoutStatus || run.WallTime >= timeout:
+		run.Outcome = OutcomeTimeout
+	case status == 137 && fields[5] == "1":
+		// SIGKILL while the kernel's OOM killer was at work in the container
+		run.Outcome = OutcomeOOM
+	case len(run.Output) > maxOutput:
+		run.Output = string(truncateUTF8([]byte(run.Output), maxOutput))
+		run.Outcome = OutcomeOutputLimit
+	case status != 0:
+		run.Outcome = OutcomeRuntimeError
+	default:
+		run.Outcome = OutcomeSuccess
+	}
+	return n, run, true
+}
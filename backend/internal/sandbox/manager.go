@@ -2,26 +2,89 @@ package sandbox
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"yz-playground/internal/logger"
+)
+
+// canaryProgram is a minimal Yz program used to verify the compile and run pipeline
+const canaryProgram = `main : {
+    println("yz-canary")
+}
+`
+
+// canaryOutput is the output expected from canaryProgram
+const canaryOutput = "yz-canary"
+
+// compilerVersionTTL is how long a fetched compiler version is reused
+const compilerVersionTTL = 5 * time.Minute
+
+// killTimeout bounds the cleanup of leftover sandbox processes
+const killTimeout = 10 * time.Second
+
+// Errors returned for executions the manager refuses to run
+var (
+	ErrShuttingDown = errors.New("sandbox manager is shutting down")
+	ErrQueueFull    = errors.New("too many executions waiting for a sandbox")
+	ErrBusy         = errors.New("every sandbox slot is busy")
 )
 
+// Observer receives execution events, e.g. to record metrics
+type Observer interface {
+	ExecutionFinished(result *ExecutionResult)
+	ExecutionFailed(err error)
+	CacheLookup(cache string, hit bool)
+}
+
 // Manager manages multiple sandbox instances
 type Manager struct {
 	sandboxes map[string]*Sandbox
 	mutex     sync.RWMutex
 	config    *SandboxConfig
+	slots     chan struct{}
+	queued    atomic.Int64 // executions waiting for a slot
+	observer  Observer
+
+	versionMutex sync.Mutex
+	versions     map[string]compilerVersion // by compiler path, "" for the sandbox's own
+
+	// ctx is canceled to abort executions still running when the drain period ends
+	ctx        context.Context
+	cancel     context.CancelFunc
+	drainMutex sync.Mutex
+	draining   bool
+	inflight   sync.WaitGroup
 }
 
 // NewManager creates a new sandbox manager
 func NewManager(config *SandboxConfig) *Manager {
+	// Each execution gets a directory of its own, so the limit only bounds the load on the container
+	concurrency := config.MaxConcurrentExecutions
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Manager{
 		sandboxes: make(map[string]*Sandbox),
+		versions:  make(map[string]compilerVersion),
 		config:    config,
+		slots:     make(chan struct{}, concurrency),
+		ctx:       ctx,
+		cancel:    cancel,
 	}
 }
 
+// SetObserver registers an observer for execution events
+func (m *Manager) SetObserver(observer Observer) {
+	m.observer = observer
+}
+
 // GetSandbox gets or creates a sandbox instance
 func (m *Manager) GetSandbox(id string) (*Sandbox, error) {
 	m.mutex.Lock()
@@ -40,21 +103,6 @@ func (m *Manager) GetSandbox(id string) (*Sandbox, error) {
 	return sandbox, nil
 }
 
-// RemoveSandbox removes a sandbox instance
-func (m *Manager) RemoveSandbox(id string) error {
-	m.mutex.Lock()
-	defer m.mutex.Unlock()
-
-	if sandbox, exists := m.sandboxes[id]; exists {
-		if err := sandbox.Close(); err != nil {
-			return fmt.Errorf("failed to close sandbox: %w", err)
-		}
-		delete(m.sandboxes, id)
-	}
-
-	return nil
-}
-
 // Cleanup removes all sandbox instances
 func (m *Manager) Cleanup() error {
 	m.mutex.Lock()
@@ -71,40 +119,280 @@ func (m *Manager) Cleanup() error {
 	return lastErr
 }
 
-// GetStats returns statistics about active sandboxes
-func (m *Manager) GetStats() map[string]interface{} {
-	m.mutex.RLock()
-	defer m.mutex.RUnlock()
+// EnsureContainer starts the sandbox container, creating it if needed
+func (m *Manager) EnsureContainer(ctx context.Context) error {
+	sandbox, err := m.GetSandbox("default")
+	if err != nil {
+		return fmt.Errorf("failed to get sandbox: %w", err)
+	}
+	return sandbox.EnsureContainer(ctx)
+}
 
-	return map[string]interface{}{
-		"active_sandboxes":   len(m.sandboxes),
-		"max_memory":         m.config.MaxMemory,
-		"max_execution_time": m.config.MaxExecutionTime,
+// CheckContainer checks that the sandbox container exists and is running
+func (m *Manager) CheckContainer(ctx context.Context) error {
+	sandbox, err := m.GetSandbox("default")
+	if err != nil {
+		return fmt.Errorf("failed to get sandbox: %w", err)
 	}
+	return sandbox.CheckContainer(ctx)
+}
+
+// RunningExecutions returns the number of executions holding a slot
+func (m *Manager) RunningExecutions() int {
+	return len(m.slots)
 }
 
-// ExecuteWithTimeout executes code with a timeout
-func (m *Manager) ExecuteWithTimeout(ctx context.Context, code string, timeout time.Duration) (*ExecutionResult, error) {
-	return m.ExecuteWithOptions(ctx, code, timeout, false)
+// QueuedExecutions returns the number of executions waiting for a slot
+func (m *Manager) QueuedExecutions() int {
+	return int(m.queued.Load())
 }
 
 // ExecuteWithOptions executes code with additional options
-func (m *Manager) ExecuteWithOptions(ctx context.Context, code string, timeout time.Duration, showGeneratedCode bool) (*ExecutionResult, error) {
+func (m *Manager) ExecuteWithOptions(ctx context.Context, code string, opts ExecutionOptions) (*ExecutionResult, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = time.Duration(m.config.MaxExecutionTime) * time.Second
+	}
+	if opts.MaxMemory <= 0 {
+		opts.MaxMemory = m.config.MaxMemory
+	}
+
+	log := logger.FromContext(ctx)
+
+	release, err := m.begin()
+	if err != nil {
+		m.executionFailed(err)
+		return nil, err
+	}
+	defer release()
+
+	// Executions still running when the drain period ends are canceled
+	ctx, stop := m.withShutdown(ctx)
+	defer stop()
+
+	// Wait for an execution slot; the wait doesn't count against the timeout
+	queueStart := time.Now()
+	if err := m.acquire(ctx); err != nil {
+		m.executionFailed(err)
+		return nil, err
+	}
+	defer func() { <-m.slots }()
+	queueTime := time.Since(queueStart)
+
+	log.Info("Execution started",
+		"code_size", len(code),
+		"timeout_ms", opts.Timeout.Milliseconds(),
+		"max_memory", opts.MaxMemory,
+		"show_generated_code", opts.ShowGeneratedCode,
+		"compile_only", opts.CompileOnly,
+		"files", len(opts.Files),
+		"runs", opts.Runs,
+		"profile", opts.Profile,
+		"cases", len(opts.Cases),
+		"queue_ms", queueTime.Milliseconds(),
+	)
+
 	// Create context with timeout
-	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+	timeoutCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
 	defer cancel()
 
 	// Use existing sandbox instance for execution
 	sandbox, err := m.GetSandbox("default")
 	if err != nil {
+		log.Error("Failed to get sandbox", "error", err)
+		m.executionFailed(err)
 		return nil, fmt.Errorf("failed to get sandbox: %w", err)
 	}
 
 	// Execute code
-	result, err := sandbox.ExecuteCodeWithOptions(timeoutCtx, code, showGeneratedCode)
+	result, err := sandbox.ExecuteCodeWithOptions(timeoutCtx, code, opts)
 	if err != nil {
+		log.Error("Execution failed", "error", err)
+		m.executionFailed(err)
 		return nil, fmt.Errorf("execution failed: %w", err)
 	}
+	result.QueueTime = int(queueTime.Milliseconds())
+	result.ExecutionTime += result.QueueTime
 
+	log.Info("Execution finished",
+		"success", result.Success,
+		"outcome", result.Outcome,
+		"execution_time_ms", result.ExecutionTime,
+		"compile_time_ms", result.CompileTime,
+		"run_time_ms", result.RunTime,
+		"memory_used", result.MemoryUsed,
+		"output_size", len(result.Output),
+	)
+	if m.observer != nil {
+		m.observer.ExecutionFinished(result)
+	}
 	return result, nil
 }
+
+// acquire takes an execution slot, waiting in the queue while all are busy
+// unless the queue is full
+func (m *Manager) acquire(ctx context.Context) error {
+	select {
+	case m.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	if m.queued.Add(1) > int64(m.config.MaxQueuedExecutions) {
+		m.queued.Add(-1)
+		return ErrQueueFull
+	}
+	defer m.queued.Add(-1)
+
+	select {
+	case m.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("canceled while waiting for a sandbox: %w", ctx.Err())
+	}
+}
+
+// Canary compiles and runs a hello-world program, bypassing the observer so
+// health checks don't show up in execution metrics. It never waits for a
+// slot, so it can't hold up executions; it returns ErrBusy if none is free.
+func (m *Manager) Canary(ctx context.Context) error {
+	select {
+	case m.slots <- struct{}{}:
+	default:
+		return ErrBusy
+	}
+	defer func() { <-m.slots }()
+
+	sandbox, err := m.GetSandbox("default")
+	if err != nil {
+		return fmt.Errorf("failed to get sandbox: %w", err)
+	}
+
+	result, err := sandbox.ExecuteCode(ctx, canaryProgram)
+	if err != nil {
+		return err
+	}
+	if !result.Success {
+		return fmt.Errorf("canary %s: %s", result.Outcome, result.Error)
+	}
+	if !strings.Contains(result.Output, canaryOutput) {
+		return fmt.Errorf("canary produced unexpected output: %q", result.Output)
+	}
+	return nil
+}
+
+// begin registers an in-flight execution, refusing new ones once shutdown has begun
+func (m *Manager) begin() (func(), error) {
+	m.drainMutex.Lock()
+	defer m.drainMutex.Unlock()
+
+	if m.draining {
+		return nil, ErrShuttingDown
+	}
+	m.inflight.Add(1)
+	return m.inflight.Done, nil
+}
+
+// withShutdown derives a context that is also canceled when the manager aborts executions
+func (m *Manager) withShutdown(ctx context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(m.ctx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// Draining reports whether shutdown has begun
+func (m *Manager) Draining() bool {
+	m.drainMutex.Lock()
+	defer m.drainMutex.Unlock()
+	return m.draining
+}
+
+// Shutdown stops accepting executions and waits for running ones until ctx is
+// done, then cancels the rest, kills any processes left in the sandbox
+// container and closes the Docker clients
+func (m *Manager) Shutdown(ctx context.Context) error {
+	log := logger.FromContext(ctx)
+
+	m.drainMutex.Lock()
+	m.draining = true
+	m.drainMutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Info("All executions finished")
+	case <-ctx.Done():
+		log.Warn("Drain period expired, canceling running executions")
+		m.cancel()
+		select {
+		case <-done:
+		case <-time.After(killTimeout):
+			log.Error("Executions did not stop after cancellation")
+		}
+	}
+	m.cancel()
+
+	killCtx, cancel := context.WithTimeout(context.Background(), killTimeout)
+	defer cancel()
+	if sandbox, err := m.GetSandbox("default"); err == nil {
+		if err := sandbox.KillProcesses(killCtx); err != nil {
+			log.Error("Failed to kill leftover sandbox processes", "error", err)
+		}
+	}
+
+	return m.Cleanup()
+}
+
+// executionFailed notifies the observer of an execution that produced no result
+func (m *Manager) executionFailed(err error) {
+	if m.observer != nil {
+		m.observer.ExecutionFailed(err)
+	}
+}
+
+// compilerVersion is a fetched compiler version and when it was fetched
+type compilerVersion struct {
+	version   string
+	fetchedAt time.Time
+}
+
+// CompilerVersion returns the version of the yzc at compilerPath in the
+// container, or of the sandbox's own when it is empty, reusing a recently
+// fetched value
+func (m *Manager) CompilerVersion(ctx context.Context, compilerPath string) (string, error) {
+	m.versionMutex.Lock()
+	defer m.versionMutex.Unlock()
+
+	if cached, ok := m.versions[compilerPath]; ok && time.Since(cached.fetchedAt) < compilerVersionTTL {
+		m.cacheLookup("compiler_version", true)
+		return cached.version, nil
+	}
+	m.cacheLookup("compiler_version", false)
+
+	sandbox, err := m.GetSandbox("default")
+	if err != nil {
+		return "", fmt.Errorf("failed to get sandbox: %w", err)
+	}
+
+	version, err := sandbox.GetCompilerVersion(ctx, compilerPath)
+	if err != nil {
+		return "", err
+	}
+
+	m.versions[compilerPath] = compilerVersion{version: version, fetchedAt: time.Now()}
+	return version, nil
+}
+
+// cacheLookup notifies the observer of a cache hit or miss
+func (m *Manager) cacheLookup(cache string, hit bool) {
+	if m.observer != nil {
+		m.observer.CacheLookup(cache, hit)
+	}
+}
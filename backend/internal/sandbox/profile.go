@@ -0,0 +1,108 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path"
+	"strconv"
+)
+
+// Files the profiled program writes its profiles to, in its project directory
+const (
+	cpuProfileFile  = "yz-cpu.pprof"
+	heapProfileFile = "yz-heap.pprof"
+)
+
+// maxProfileSize bounds the bytes of a profile fetched from the container
+const maxProfileSize = 8 << 20
+
+// profileHarness is added to the generated Go program, whose main is renamed
+// yzMain and kept from being inlined so it shows in profiles. It records a
+// CPU profile while yzMain runs and a heap profile once it returns.
+// Allocations are sampled every 4 KB rather than the default 512 KB, so the
+// small programs of the playground still show up.
+const profileHarness = `package main
+
+import (
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+func init() {
+	runtime.MemProfileRate = 4096
+}
+
+func main() {
+	if f, err := os.Create("` + cpuProfileFile + `"); err == nil {
+		if pprof.StartCPUProfile(f) == nil {
+			defer f.Close()
+			defer pprof.StopCPUProfile()
+		}
+	}
+	defer func() {
+		if f, err := os.Create("` + heapProfileFile + `"); err == nil {
+			runtime.GC()
+			pprof.Lookup("heap").WriteTo(f, 0)
+			f.Close()
+		}
+	}()
+	yzMain()
+}
+`
+
+// profileScript builds the program with yzc build, then rebuilds the Go code
+// it generated with profileHarness in place of its main and runs the result.
+// Profiles are only written if the program's main returns.
+const profileScript = buildScript + `src=$(grep -rl --include='*.go' '^func main()' . | head -n 1)
+[ -n "$src" ] || { echo "yzc build left no Go source to profile" >&2; exit 1; }
+sed -i 's|^func main()|//go:noinline\nfunc yzMain()|' "$src"
+cat > "$(dirname "$src")/yz_profile.go" <<'YZ_PROFILE_EOF'
+` + profileHarness + `YZ_PROFILE_EOF
+bin=$PWD/yz-profiled
+(cd "$(dirname "$src")" && if [ -f go.mod ]; then go build -o "$bin" .; else go build -o "$bin" ./*.go; fi) || exit 1
+` + startScript + `exec "$bin"`
+
+// Profile holds the pprof profiles of a profiled run, gzip-compressed as the
+// Go runtime writes them. Either is nil if the program didn't write it.
+type Profile struct {
+	CPU  []byte
+	Heap []byte
+}
+
+// readProfile fetches the profiles a profiled run wrote to dir in the
+// container. It runs even if the run was canceled, like removeDir.
+func (s *Sandbox) readProfile(ctx context.Context, dir string) (*Profile, error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), killTimeout)
+	defer cancel()
+
+	cpu, err := s.readProfileFile(ctx, path.Join(dir, cpuProfileFile))
+	if err != nil {
+		return nil, err
+	}
+	heap, err := s.readProfileFile(ctx, path.Join(dir, heapProfileFile))
+	if err != nil {
+		return nil, err
+	}
+	return &Profile{CPU: cpu, Heap: heap}, nil
+}
+
+// readProfileFile returns the contents of a profile in the container, or nil
+// if there is no such regular file. The program could have replaced the file,
+// so it is read as the sandbox user and symlinks are refused.
+func (s *Sandbox) readProfileFile(ctx context.Context, file string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "docker", "exec", "-u", "yzuser", s.config.ContainerName,
+		"bash", "-c", `[ -f "$1" ] && [ ! -L "$1" ] || exit 0; head -c "$2" "$1"`, "yz-profile", file, strconv.Itoa(maxProfileSize+1))
+	data, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path.Base(file), err)
+	}
+	if len(data) > maxProfileSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", path.Base(file), maxProfileSize)
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	return data, nil
+}
@@ -0,0 +1,164 @@
+package sandbox
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// MainFile is the entry point of every execution; a request's code is written to it
+const MainFile = "main.yz"
+
+// buildScript builds the program in the current directory with yzc build and
+// sets app to the program it reports building. $1 is the compiler. A script
+// running as root sets as_user to build as the sandbox user.
+const buildScript = `out=$($as_user "$1" build 2>&1); status=$?; echo "$out"; [ $status -eq 0 ] || exit $status
+app=${out##*Built: }; app=${app%%[[:space:]]*}
+[ -x "$app" ] || { echo "yzc build did not report a program to run" >&2; exit 1; }
+`
+
+// startScript tells the output recorder that compilation is done, as yzc
+// does for a single file
+const startScript = `echo "` + runMarker + `"
+`
+
+// projectScript builds a multi-file project and runs its program
+const projectScript = buildScript + startScript + `exec "$app"`
+
+// scriptEnv is the environment variable carrying a build script such as
+// projectScript into the container, which spares quoting it inside the run's
+// shell command
+const scriptEnv = "YZ_SCRIPT"
+
+// CheckProjectFiles returns an error describing the first of files that
+// breaks the limits or whose path isn't a plain relative path inside the
+// project. Paths use forward slashes, may only contain letters, digits, '.',
+// '-' and '_', must end in .yz and can't name main.yz, which holds the
+// request's code.
+func CheckProjectFiles(files map[string]string, maxFiles, maxDepth int) error {
+	if len(files) > maxFiles {
+		return fmt.Errorf("a project may have at most %d files besides %s, got %d", maxFiles, MainFile, len(files))
+	}
+
+	// Sort paths so the same request always reports the same error
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	dirs := make(map[string]bool)
+	for _, p := range paths {
+		if err := checkProjectPath(p, maxDepth); err != nil {
+			return fmt.Errorf("file %q: %w", p, err)
+		}
+		for dir := path.Dir(p); dir != "."; dir = path.Dir(dir) {
+			dirs[dir] = true
+		}
+	}
+	for _, p := range paths {
+		if dirs[p] || strings.HasPrefix(p, MainFile+"/") {
+			return fmt.Errorf("file %q: path is also used as a directory", p)
+		}
+	}
+	return nil
+}
+
+// checkProjectPath checks one project file path
+func checkProjectPath(p string, maxDepth int) error {
+	switch {
+	case p == "":
+		return errors.New("path is empty")
+	case strings.HasPrefix(p, "/"):
+		return errors.New("path must be relative")
+	case p == ".." || strings.HasPrefix(p, "../"):
+		return errors.New("path must stay inside the project")
+	case path.Clean(p) != p:
+		return errors.New("path must be clean, without empty, . or .. segments")
+	case p == MainFile:
+		return fmt.Errorf("%s comes from the request's code", MainFile)
+	case path.Ext(p) != ".yz":
+		return errors.New("only .yz files can be part of a project")
+	}
+	for _, r := range p {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("._-/", r)) {
+			return fmt.Errorf("path contains %q; only letters, digits, '.', '-', '_' and '/' are allowed", r)
+		}
+	}
+
+	segments := strings.Split(p, "/")
+	if len(segments) > maxDepth {
+		return fmt.Errorf("path is %d levels deep, more than %d", len(segments), maxDepth)
+	}
+	for _, segment := range segments {
+		if strings.HasPrefix(segment, ".") {
+			return errors.New("path segments can't start with '.'")
+		}
+	}
+	return nil
+}
+
+// writeProjectFiles writes the files of a multi-file project into dir
+func writeProjectFiles(dir string, files map[string]string) error {
+	for p, content := range files {
+		file := filepath.Join(dir, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", p, err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// projectArchive returns a tar archive of the files in dir, placed under
+// prefix. Directories are writable by anyone, so the sandbox user can build
+// in them although the copy into the container creates them as root; a judged
+// run makes them root's and read-only once the build is done.
+func projectArchive(dir, prefix string) (*bytes.Buffer, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+
+	if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: prefix + "/", Mode: 0777}); err != nil {
+		return nil, fmt.Errorf("failed to write tar header: %w", err)
+	}
+	err := filepath.WalkDir(dir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil || file == dir {
+			return err
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		name := path.Join(prefix, filepath.ToSlash(rel))
+
+		if entry.IsDir() {
+			return tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: name + "/", Mode: 0777})
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Size: int64(len(data)), Mode: 0644}); err != nil {
+			return err
+		}
+		_, err = tw.Write(data)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to archive project: %w", err)
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close tar writer: %w", err)
+	}
+	return &buf, nil
+}
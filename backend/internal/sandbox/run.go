@@ -0,0 +1,331 @@
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"yz-playground/internal/tracing"
+	"yz-playground/pkg/api"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Execution outcomes reported in ExecutionResult.Outcome
+const (
+	OutcomeSuccess      = "success"
+	OutcomeCompileError = "compile_error"
+	OutcomeRuntimeError = "runtime_error"
+	OutcomeTimeout      = "timeout"
+	OutcomeOOM          = "oom"
+	OutcomeOutputLimit  = "output_limit"
+	OutcomeCanceled     = "canceled"
+)
+
+// runMarker is printed by yzc once compilation is done and the program starts
+const runMarker = "running generated app"
+
+// truncationMarker ends the output of a run stopped for printing too much
+const truncationMarker = "[output truncated at %d bytes]"
+
+// statsPrefix tags the resource usage line appended by GNU time
+const statsPrefix = "__YZ_MAXRSS_KB__="
+
+// containerRun holds what was observed while running a command in the container
+type containerRun struct {
+	rawOutput      string
+	output         string
+	generatedCode  string
+	outcome        string
+	programStarted bool // whether the program started after compilation
+	truncated      bool // whether output passed the limit and the run was stopped
+	oomKilled      bool // whether the OOM killer killed a process in the container during the run
+	compileTime    time.Duration
+	runTime        time.Duration
+	memoryUsed     int64 // in bytes
+	runs           []RunStats
+	cases          []CaseRun
+
+	startedAt        time.Time
+	programStartedAt time.Time // zero if the program never started
+	finishedAt       time.Time
+}
+
+// sessionGrace is how long past its timeout a run may keep running in the
+// container before it kills itself, in case the backend can't stop it
+const sessionGrace = 5 * time.Second
+
+// oomKillsFunc defines a shell function that prints how many processes the
+// kernel's OOM killer has killed in the container's cgroup, under cgroup v2 or
+// v1, or nothing if the count isn't available
+const oomKillsFunc = `oom_kills() { cat /sys/fs/cgroup/memory.events /sys/fs/cgroup/memory/memory.oom_control 2>/dev/null | sed -n 's/^oom_kill //p' | head -n 1; }
+`
+
+// oomMarker is the line the session script adds after the output when the
+// OOM killer killed a process in the container while the command ran
+const oomMarker = "__YZ_OOM_KILLED__"
+
+// sessionScript runs a command in the new session started by setsid. It records
+// the session ID in the pid file and removes the file once the command is done;
+// timeout kills the command's whole process group when the grace period ends.
+const sessionScript = oomKillsFunc + `echo $$ > "$1"; kills=$(oom_kills)
+timeout -s KILL "$2" bash -c "$3"; status=$?; rm -f "$1"
+[ "$(oom_kills)" = "$kills" ] || printf '\n%s\n' "` + oomMarker + `"
+exit $status`
+
+// killScript kills every process in the session recorded in a pid file
+const killScript = `[ -s "$1" ] || exit 0; pkill -KILL -s "$(cat "$1")"; rm -f "$1"`
+
+// inSession returns the arguments that run a shell command in its own session
+// inside the container, so that killSession can stop every process it starts
+func inSession(command, pidFile string, timeout time.Duration) []string {
+	deadline := strconv.FormatFloat((timeout+sessionGrace).Seconds(), 'f', 3, 64) + "s"
+	return []string{"setsid", "--wait", "bash", "-c", sessionScript, "yz-run", pidFile, deadline, command}
+}
+
+// newPIDFile returns a unique path for the pid file of a run
+func newPIDFile() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "/tmp/yz-run-" + strconv.FormatInt(time.Now().UnixNano(), 10) + ".pid"
+	}
+	return "/tmp/yz-run-" + hex.EncodeToString(b) + ".pid"
+}
+
+// withResourceStats wraps a shell command so its peak memory is reported when GNU time is available
+func withResourceStats(command string) string {
+	return "if [ -x /usr/bin/time ]; then /usr/bin/time -f '" + statsPrefix + "%M' " + command +
+		"; else " + command + "; fi"
+}
+
+// outputRecorder collects combined output and notes when the program starts running.
+// When stream is set, the program's output is also forwarded to it line by line.
+// Output past limit bytes is discarded and onLimit is called to stop the run.
+type outputRecorder struct {
+	mutex            sync.Mutex
+	buf              bytes.Buffer
+	start            time.Time
+	programStartedAt time.Time
+	scanFrom         int
+	programStarted   bool
+
+	stream     io.Writer
+	streamFrom int // offset in buf of the first byte not yet forwarded
+
+	limit     int // 0 for no limit
+	onLimit   func()
+	truncated bool
+}
+
+// newOutputRecorder creates a recorder whose clock starts now
+func newOutputRecorder(stream io.Writer, limit int, onLimit func()) *outputRecorder {
+	return &outputRecorder{start: time.Now(), stream: stream, limit: limit, onLimit: onLimit}
+}
+
+// Write implements io.Writer. It never fails, so the command isn't left
+// blocked on a full pipe once the limit is reached.
+func (r *outputRecorder) Write(p []byte) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	n := len(p)
+	if r.truncated {
+		return n, nil
+	}
+	if r.limit > 0 && r.buf.Len()+len(p) > r.limit {
+		p = truncateUTF8(p, r.limit-r.buf.Len())
+		r.truncated = true
+		defer r.onLimit()
+	}
+
+	r.buf.Write(p)
+	if !r.programStarted {
+		if i := bytes.Index(r.buf.Bytes()[r.scanFrom:], []byte(runMarker)); i >= 0 {
+			r.programStarted = true
+			r.programStartedAt = time.Now()
+			// Stream from the start of the marker's line, which forward skips
+			r.streamFrom = bytes.LastIndexByte(r.buf.Bytes()[:r.scanFrom+i], '\n') + 1
+		} else if r.buf.Len() > len(runMarker) {
+			// Keep enough of the tail to catch a marker split across writes
+			r.scanFrom = r.buf.Len() - len(runMarker)
+		}
+	}
+	if r.programStarted {
+		r.forward(false)
+	}
+	return n, nil
+}
+
+// truncateUTF8 shortens p to at most size bytes without splitting a character
+func truncateUTF8(p []byte, size int) []byte {
+	if size >= len(p) {
+		return p
+	}
+	for size > 0 && !utf8.RuneStart(p[size]) {
+		size--
+	}
+	return p[:size]
+}
+
+// forward writes the complete lines of program output not yet streamed,
+// and the trailing partial line too when final is set
+func (r *outputRecorder) forward(final bool) {
+	if r.stream == nil {
+		return
+	}
+	data := r.buf.Bytes()[r.streamFrom:]
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, '\n') + 1
+		if end == 0 {
+			if !final {
+				return
+			}
+			end = len(data)
+		}
+		line := data[:end]
+		if !bytes.Contains(line, []byte(runMarker)) && !bytes.Contains(line, []byte(oomMarker)) && !isResourceStatsLine(string(line)) {
+			// A client that went away shouldn't fail the execution
+			_, _ = r.stream.Write(line)
+		}
+		r.streamFrom += end
+		data = data[end:]
+	}
+}
+
+// finish stops the clock and extracts timings and resource stats from the output
+func (r *outputRecorder) finish() *containerRun {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	end := time.Now()
+	if r.programStarted {
+		r.forward(true)
+	}
+	run := &containerRun{
+		programStarted:   r.programStarted,
+		truncated:        r.truncated,
+		startedAt:        r.start,
+		programStartedAt: r.programStartedAt,
+		finishedAt:       end,
+	}
+	if r.programStarted {
+		run.compileTime = r.programStartedAt.Sub(r.start)
+		run.runTime = end.Sub(r.programStartedAt)
+	} else {
+		run.compileTime = end.Sub(r.start)
+	}
+
+	run.rawOutput, run.oomKilled = strings.CutSuffix(r.buf.String(), "\n"+oomMarker+"\n")
+	run.rawOutput, run.memoryUsed = extractResourceStats(run.rawOutput)
+	return run
+}
+
+// extractResourceStats removes GNU time's lines from output and returns the peak memory in bytes
+func extractResourceStats(output string) (string, int64) {
+	if !strings.Contains(output, statsPrefix) {
+		return output, 0
+	}
+
+	var memoryUsed int64
+	lines := strings.Split(output, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if value, ok := strings.CutPrefix(trimmed, statsPrefix); ok {
+			if kb, err := strconv.ParseInt(value, 10, 64); err == nil {
+				memoryUsed = kb * 1024
+			}
+			continue
+		}
+		if isResourceStatsLine(line) {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n"), memoryUsed
+}
+
+// isResourceStatsLine reports whether line was written by GNU time rather than the program
+func isResourceStatsLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, statsPrefix) ||
+		strings.HasPrefix(trimmed, "Command exited with non-zero status") ||
+		strings.HasPrefix(trimmed, "Command terminated by signal")
+}
+
+// recordSpans adds compile and run spans to ctx's trace using the times observed
+// in the output, since both steps happen inside a single yzc invocation
+func (run *containerRun) recordSpans(ctx context.Context) {
+	compileEnd := run.finishedAt
+	if run.programStarted {
+		compileEnd = run.programStartedAt
+	}
+
+	var compileErr, runErr error
+	if run.outcome != OutcomeSuccess {
+		if run.programStarted {
+			runErr = errors.New(run.outcome)
+		} else {
+			compileErr = errors.New(run.outcome)
+		}
+	}
+
+	_, compileSpan := tracing.Start(ctx, "sandbox.compile", trace.WithTimestamp(run.startedAt))
+	tracing.EndSpan(compileSpan, compileErr, trace.WithTimestamp(compileEnd))
+
+	if run.programStarted {
+		_, runSpan := tracing.Start(ctx, "sandbox.run", trace.WithTimestamp(run.programStartedAt),
+			trace.WithAttributes(attribute.Int64("memory_used_bytes", run.memoryUsed)))
+		tracing.EndSpan(runSpan, runErr, trace.WithTimestamp(run.finishedAt))
+	}
+}
+
+// classifyFailure maps a failed run to an outcome
+func classifyFailure(ctx context.Context, err error, run *containerRun) string {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return OutcomeTimeout
+	case errors.Is(ctx.Err(), context.Canceled):
+		return OutcomeCanceled
+	}
+
+	var exitError *exec.ExitError
+	if errors.As(err, &exitError) && exitError.ExitCode() == 137 && run.oomKilled {
+		// SIGKILL while the kernel's OOM killer was at work in the container;
+		// any other SIGKILL is an ordinary runtime error
+		return OutcomeOOM
+	}
+
+	if run.programStarted {
+		return OutcomeRuntimeError
+	}
+	return OutcomeCompileError
+}
+
+// errorCode maps the outcome of a failed run to its API error code
+func errorCode(outcome string) api.ErrorCode {
+	switch outcome {
+	case OutcomeCompileError:
+		return api.CodeCompileError
+	case OutcomeRuntimeError:
+		return api.CodeRuntimeError
+	case OutcomeTimeout:
+		return api.CodeTimeout
+	case OutcomeOOM:
+		return api.CodeMemoryLimit
+	case OutcomeOutputLimit:
+		return api.CodeOutputLimit
+	}
+	// Runs are only canceled when the client goes away or the server shuts down
+	return api.CodeSandboxUnavailable
+}
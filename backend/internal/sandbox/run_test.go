@@ -0,0 +1,77 @@
+package sandbox
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// TestTimeoutLeavesNoProcesses runs a program that starts children which
+// outlive its timeout, and checks that none of them is left in the sandbox
+// container afterwards. It starts a throwaway container from the sandbox
+// image, named by SANDBOX_IMAGE or localhost/yz-sandbox, and is skipped when
+// Docker or the image isn't available.
+func TestTimeoutLeavesNoProcesses(t *testing.T) {
+	image := os.Getenv("SANDBOX_IMAGE")
+	if image == "" {
+		image = "localhost/yz-sandbox"
+	}
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	name := "yz-sandbox-test-" + hex.EncodeToString(suffix)
+
+	s, err := New(&SandboxConfig{
+		ContainerName: name,
+		Image:         image,
+		WorkingDir:    "/workspace",
+		// Stands in for yzc: starts children that sleep past the timeout
+		CompilerPath:     `bash -c 'for i in 1 2 3; do sleep 300 & done; sleep 300' yz-children`,
+		MaxMemory:        256 << 20,
+		MaxExecutionTime: 10,
+		MaxOutputSize:    1 << 20,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer s.Close()
+
+	ctx := context.Background()
+	if err := s.Ping(ctx); err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+	if _, err := s.client.ImageInspect(ctx, image); err != nil {
+		t.Skipf("sandbox image %s not available: %v", image, err)
+	}
+	if err := s.EnsureContainer(ctx); err != nil {
+		t.Fatalf("EnsureContainer: %v", err)
+	}
+	defer s.client.ContainerRemove(context.WithoutCancel(ctx), name, container.RemoveOptions{Force: true})
+
+	result, err := s.ExecuteCodeWithOptions(ctx, "main: {\n}\n", ExecutionOptions{Timeout: 2 * time.Second})
+	if err != nil {
+		t.Fatalf("ExecuteCodeWithOptions: %v", err)
+	}
+	if result.Outcome != OutcomeTimeout {
+		t.Fatalf("outcome = %q, want %q", result.Outcome, OutcomeTimeout)
+	}
+
+	// Killed processes may take a moment to be reaped
+	var output []byte
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(100 * time.Millisecond) {
+		output, err = exec.Command("docker", "exec", name, "pgrep", "-a", "-u", "yzuser").Output()
+		// pgrep exits with 1 when no process matched
+		if exitError, ok := err.(*exec.ExitError); ok && exitError.ExitCode() == 1 {
+			return
+		}
+		if err != nil {
+			t.Fatalf("pgrep: %v", err)
+		}
+	}
+	t.Fatalf("processes left running as yzuser:\n%s", output)
+}
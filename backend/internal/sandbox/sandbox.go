@@ -1,38 +1,87 @@
 package sandbox
 
 import (
-	"archive/tar"
-	"bytes"
 	"context"
+	"encoding/base64"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"yz-playground/internal/compiler"
+	"yz-playground/internal/logger"
+	"yz-playground/internal/tracing"
+	"yz-playground/pkg/api"
 
 	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/client"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Sandbox represents a Docker-based sandbox for code execution
 type Sandbox struct {
-	client    *client.Client
-	imageName string
-	config    *SandboxConfig
-	compiler  *compiler.Compiler
+	client   *client.Client
+	config   *SandboxConfig
+	compiler *compiler.Compiler
 }
 
 // SandboxConfig holds sandbox configuration
 type SandboxConfig struct {
-	ImageName        string
-	MaxMemory        int64 // in bytes
-	MaxExecutionTime int   // in seconds
-	WorkingDir       string
-	CompilerPath     string
+	ContainerName    string // the long-running sandbox container executions are run in
+	Image            string // image the sandbox container is created from
+	MaxMemory        int64  // in bytes, advisory: applied as GOMEMLIMIT
+	MaxExecutionTime int    // in seconds
+	MaxOutputSize    int    // in bytes, 0 for no limit
+	WorkingDir       string // workspace directory inside the container
+	CompilerPath     string // yzc path inside the container
+
+	// MaxConcurrentExecutions bounds how many executions run at once; others wait
+	MaxConcurrentExecutions int
+	// MaxQueuedExecutions bounds how many executions may wait for a slot;
+	// further ones are rejected with ErrQueueFull
+	MaxQueuedExecutions int
+}
+
+// ExecutionOptions holds per-execution settings that override the sandbox defaults
+type ExecutionOptions struct {
+	Timeout           time.Duration
+	MaxMemory         int64 // in bytes
+	MaxOutputSize     int   // in bytes
+	ShowGeneratedCode bool
+	CompileOnly       bool // build the program without running it
+
+	// Runs, when above 0, benchmarks the program: it is built once and run
+	// this many times, and each run is reported in ExecutionResult.Runs
+	Runs int
+
+	// Files holds further source files of a multi-file project by relative
+	// path, checked with CheckProjectFiles. A project is built with yzc build,
+	// with the code as its main.yz.
+	Files map[string]string
+
+	// Cases, when set, judges the program: it is built once and run on each
+	// of these inputs in turn, each within CaseTimeout, and each run is
+	// reported in ExecutionResult.Cases. MaxOutputSize then bounds the output
+	// of each case and Timeout covers the build and every case together.
+	Cases       []string
+	CaseTimeout time.Duration
+
+	// Profile runs the program with CPU and heap profiling, returned in
+	// ExecutionResult.Profile
+	Profile bool
+
+	// Output, when set, receives the program's output as it is produced
+	Output io.Writer
+
+	// CompilerPath, when set, is the yzc inside the container to build with
+	// instead of the sandbox's own
+	CompilerPath string
 }
 
 // ExecutionResult holds the result of code execution
@@ -41,8 +90,17 @@ type ExecutionResult struct {
 	Output        string
 	GeneratedCode string
 	Error         string
-	ExecutionTime int
-	MemoryUsed    int64
+	ErrorCode     api.ErrorCode // set when Success is false
+	Outcome       string
+	Truncated     bool       // output passed the size limit and the run was stopped
+	ExecutionTime int        // in milliseconds, including queue wait
+	CompileTime   int        // in milliseconds
+	RunTime       int        // in milliseconds
+	QueueTime     int        // in milliseconds
+	MemoryUsed    int64      // peak resident memory in bytes, 0 if unknown
+	Runs          []RunStats // one per completed benchmark run
+	Profile       *Profile   // set for a profiled run whose program started
+	Cases         []CaseRun  // one per case run, in order
 }
 
 // New creates a new sandbox instance
@@ -60,22 +118,37 @@ func New(config *SandboxConfig) (*Sandbox, error) {
 	)
 
 	return &Sandbox{
-		client:    cli,
-		imageName: config.ImageName,
-		config:    config,
-		compiler:  compilerInstance,
+		client:   cli,
+		config:   config,
+		compiler: compilerInstance,
 	}, nil
 }
 
 // ExecuteCode executes Yz code in the sandbox
 func (s *Sandbox) ExecuteCode(ctx context.Context, code string) (*ExecutionResult, error) {
-	return s.ExecuteCodeWithOptions(ctx, code, false)
+	return s.ExecuteCodeWithOptions(ctx, code, ExecutionOptions{})
 }
 
 // ExecuteCodeWithOptions executes Yz code in the sandbox with additional options
-func (s *Sandbox) ExecuteCodeWithOptions(ctx context.Context, code string, showGeneratedCode bool) (*ExecutionResult, error) {
+func (s *Sandbox) ExecuteCodeWithOptions(ctx context.Context, code string, opts ExecutionOptions) (result *ExecutionResult, err error) {
 	startTime := time.Now()
 
+	ctx, span := tracing.Start(ctx, "sandbox.execute", trace.WithAttributes(
+		attribute.Int("code_size", len(code)),
+		attribute.Bool("show_generated_code", opts.ShowGeneratedCode),
+		attribute.Bool("compile_only", opts.CompileOnly),
+		attribute.Int("files", len(opts.Files)),
+		attribute.Int("runs", opts.Runs),
+		attribute.Bool("profile", opts.Profile),
+		attribute.Int("cases", len(opts.Cases)),
+	))
+	defer func() {
+		if result != nil {
+			span.SetAttributes(attribute.String("outcome", result.Outcome))
+		}
+		tracing.EndSpan(span, err)
+	}()
+
 	// Create temporary directory for execution
 	tempDir, err := s.createTempDir()
 	if err != nil {
@@ -84,7 +157,7 @@ func (s *Sandbox) ExecuteCodeWithOptions(ctx context.Context, code string, showG
 	defer os.RemoveAll(tempDir)
 
 	// Write code to file (ensure it ends with a newline)
-	codeFile := filepath.Join(tempDir, "main.yz")
+	codeFile := filepath.Join(tempDir, MainFile)
 	// Add newline if code doesn't end with one
 	if !strings.HasSuffix(code, "\n") {
 		code += "\n"
@@ -92,37 +165,81 @@ func (s *Sandbox) ExecuteCodeWithOptions(ctx context.Context, code string, showG
 	if err := os.WriteFile(codeFile, []byte(code), 0644); err != nil {
 		return nil, fmt.Errorf("failed to write code file: %w", err)
 	}
+	if err := writeProjectFiles(tempDir, opts.Files); err != nil {
+		return nil, err
+	}
+
+	log := logger.FromContext(ctx)
 
 	// Copy code to existing container's workspace
-	err = s.copyCodeToContainer(ctx, tempDir)
+	copyCtx, copySpan := tracing.Start(ctx, "sandbox.copy_code")
+	err = s.copyCodeToContainer(copyCtx, tempDir)
+	tracing.EndSpan(copySpan, err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to copy code to container: %w", err)
 	}
+	log.Debug("Copied code to container", "container", s.config.ContainerName, "bytes", len(code), "files", len(opts.Files)+1)
+	defer s.removeDir(ctx, s.projectDir(tempDir))
+	if len(opts.Cases) > 0 {
+		dir := caseDir(tempDir)
+		archive, err := caseArchive(path.Base(dir), opts.Cases)
+		if err == nil {
+			err = s.copyArchive(ctx, path.Dir(dir), archive)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy case inputs to container: %w", err)
+		}
+		defer s.removeDir(ctx, dir)
+	}
 
 	// Execute code compilation and run using existing container
-	output, generatedCode, err := s.executeInContainerWithOptions(ctx, "yz-sandbox", tempDir, showGeneratedCode)
+	run, runErr := s.executeInContainerWithOptions(ctx, s.config.ContainerName, tempDir, opts)
+	if runErr != nil {
+		log.Debug("Program failed in container", "error", runErr, "outcome", run.outcome)
+	}
 
 	executionTime := int(time.Since(startTime).Milliseconds())
 
-	result := &ExecutionResult{
-		Success:       err == nil,
-		Output:        output,
-		GeneratedCode: generatedCode,
+	result = &ExecutionResult{
+		Success:       runErr == nil,
+		Output:        run.output,
+		GeneratedCode: run.generatedCode,
+		Outcome:       run.outcome,
+		Truncated:     run.truncated,
 		ExecutionTime: executionTime,
+		CompileTime:   int(run.compileTime.Milliseconds()),
+		RunTime:       int(run.runTime.Milliseconds()),
+		MemoryUsed:    run.memoryUsed,
+		Runs:          run.runs,
+		Cases:         run.cases,
+	}
+	if opts.Profile && run.programStarted {
+		profile, profileErr := s.readProfile(ctx, s.projectDir(tempDir))
+		if profileErr != nil {
+			log.Warn("Failed to read profile", "error", profileErr)
+		}
+		result.Profile = profile
 	}
 
-	if err != nil {
-		result.Error = err.Error()
+	if runErr != nil {
+		result.Error = runErr.Error()
+		result.ErrorCode = errorCode(run.outcome)
 	}
 
 	return result, nil
 }
 
-// GetCompilerVersion returns the Yz compiler version by executing the command inside the Docker container
-func (s *Sandbox) GetCompilerVersion(ctx context.Context) (string, error) {
+// GetCompilerVersion returns the version of the yzc at compilerPath, or of
+// the sandbox's own when it is empty, by executing the command inside the
+// Docker container
+func (s *Sandbox) GetCompilerVersion(ctx context.Context, compilerPath string) (string, error) {
+	if compilerPath == "" {
+		compilerPath = s.config.CompilerPath
+	}
+
 	// Execute yzc --version command inside the Docker container
-	cmd := exec.CommandContext(ctx, "docker", "exec", "-u", "yzuser", "yz-sandbox",
-		"bash", "-c", "yzc --version")
+	cmd := exec.CommandContext(ctx, "docker", "exec", "-u", "yzuser", s.config.ContainerName,
+		compilerPath, "--version")
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -139,155 +256,304 @@ func (s *Sandbox) GetCompilerVersion(ctx context.Context) (string, error) {
 	return strings.TrimSpace(string(output)), nil
 }
 
-// ValidateCompiler validates that the compiler is working
-func (s *Sandbox) ValidateCompiler(ctx context.Context) error {
-	return s.compiler.ValidateCompiler(ctx)
+// Ping checks that the Docker daemon is reachable
+func (s *Sandbox) Ping(ctx context.Context) error {
+	if _, err := s.client.Ping(ctx); err != nil {
+		return fmt.Errorf("docker daemon unreachable: %w", err)
+	}
+	return nil
 }
 
-// createTempDir creates a temporary directory for execution
-func (s *Sandbox) createTempDir() (string, error) {
-	tempDir, err := os.MkdirTemp("", "yz-execution-*")
+// CheckContainer checks that the sandbox container exists, was created from
+// the sandbox image and is running
+func (s *Sandbox) CheckContainer(ctx context.Context) error {
+	info, err := s.client.ContainerInspect(ctx, s.config.ContainerName)
 	if err != nil {
-		return "", err
+		return fmt.Errorf("failed to inspect sandbox container: %w", err)
 	}
-	return tempDir, nil
+	if info.Config != nil && info.Config.Image != s.config.Image {
+		return fmt.Errorf("sandbox container runs image %s, not %s", info.Config.Image, s.config.Image)
+	}
+	if info.State == nil || !info.State.Running {
+		status := "unknown"
+		if info.State != nil {
+			status = info.State.Status
+		}
+		return fmt.Errorf("sandbox container is not running (status: %s)", status)
+	}
+	return nil
 }
 
-// createContainer creates a Docker container for execution
-func (s *Sandbox) createContainer(ctx context.Context, tempDir string) (string, error) {
-	containerConfig := &container.Config{
-		Image:      s.imageName,
-		Cmd:        []string{"sleep", "infinity"},
-		WorkingDir: "/workspace",
-		User:       "yzuser",
-	}
-
-	hostConfig := &container.HostConfig{
-		Privileged: true, // Required for isolate
-		Resources: container.Resources{
-			Memory:     s.config.MaxMemory,
-			MemorySwap: s.config.MaxMemory,
-		},
-		Mounts: []mount.Mount{
-			{
-				Type:   mount.TypeBind,
-				Source: tempDir,
-				Target: "/workspace",
-			},
-		},
-		AutoRemove: false, // We'll remove manually
-	}
-
-	resp, err := s.client.ContainerCreate(ctx, containerConfig, hostConfig, nil, nil, "")
-	if err != nil {
-		return "", err
+// EnsureContainer starts the sandbox container if it isn't running, creating
+// it from the sandbox image first if it doesn't exist. The container gets an
+// init process to reap the orphans of killed runs, and no network.
+func (s *Sandbox) EnsureContainer(ctx context.Context) error {
+	info, err := s.client.ContainerInspect(ctx, s.config.ContainerName)
+	switch {
+	case client.IsErrNotFound(err):
+		useInit := true
+		_, err = s.client.ContainerCreate(ctx, &container.Config{
+			Image:      s.config.Image,
+			Cmd:        []string{"sleep", "infinity"},
+			WorkingDir: s.config.WorkingDir,
+		}, &container.HostConfig{
+			Init:          &useInit,
+			NetworkMode:   "none",
+			RestartPolicy: container.RestartPolicy{Name: container.RestartPolicyUnlessStopped},
+		}, nil, nil, s.config.ContainerName)
+		if err != nil {
+			return fmt.Errorf("failed to create sandbox container from %s: %w", s.config.Image, err)
+		}
+		logger.FromContext(ctx).Info("Created sandbox container", "container", s.config.ContainerName, "image", s.config.Image)
+	case err != nil:
+		return fmt.Errorf("failed to inspect sandbox container: %w", err)
+	case info.State != nil && info.State.Running:
+		return nil
 	}
 
-	// Start container
-	if err := s.client.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
-		return "", err
+	if err := s.client.ContainerStart(ctx, s.config.ContainerName, container.StartOptions{}); err != nil {
+		return fmt.Errorf("failed to start sandbox container: %w", err)
 	}
-
-	return resp.ID, nil
+	return nil
 }
 
-// copyCodeToContainer copies the code file to the container's workspace
-func (s *Sandbox) copyCodeToContainer(ctx context.Context, tempDir string) error {
-	codeFile := filepath.Join(tempDir, "main.yz")
+// killSession kills every process started by the run whose session ID is in pidFile
+func (s *Sandbox) killSession(ctx context.Context, containerID, pidFile string) error {
+	cmd := exec.CommandContext(ctx, "docker", "exec", "-u", "root", containerID,
+		"bash", "-c", killScript, "yz-kill", pidFile)
 
-	// Read the code file
-	codeData, err := os.ReadFile(codeFile)
-	if err != nil {
-		return fmt.Errorf("failed to read code file: %w", err)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to kill execution processes: %w: %s", err, strings.TrimSpace(string(output)))
 	}
+	return nil
+}
 
-	// Create a tar archive with the code file
-	var buf bytes.Buffer
-	tw := tar.NewWriter(&buf)
+// KillProcesses kills every process run by the sandbox user in the container
+func (s *Sandbox) KillProcesses(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, "docker", "exec", "-u", "root", s.config.ContainerName,
+		"pkill", "-KILL", "-u", "yzuser")
 
-	// Create tar header
-	header := &tar.Header{
-		Name: "main.yz",
-		Size: int64(len(codeData)),
-		Mode: 0644,
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		// pkill exits with 1 when no process matched
+		if exitError, ok := err.(*exec.ExitError); ok && exitError.ExitCode() == 1 {
+			return nil
+		}
+		return fmt.Errorf("failed to kill sandbox processes: %w: %s", err, strings.TrimSpace(string(output)))
 	}
 
-	if err := tw.WriteHeader(header); err != nil {
-		return fmt.Errorf("failed to write tar header: %w", err)
-	}
+	logger.FromContext(ctx).Info("Killed leftover sandbox processes", "container", s.config.ContainerName)
+	return nil
+}
 
-	if _, err := tw.Write(codeData); err != nil {
-		return fmt.Errorf("failed to write code to tar: %w", err)
+// ValidateCompiler validates that the compiler is working
+func (s *Sandbox) ValidateCompiler(ctx context.Context) error {
+	return s.compiler.ValidateCompiler(ctx)
+}
+
+// createTempDir creates a temporary directory for execution
+func (s *Sandbox) createTempDir() (string, error) {
+	tempDir, err := os.MkdirTemp("", "yz-execution-*")
+	if err != nil {
+		return "", err
 	}
+	return tempDir, nil
+}
 
-	if err := tw.Close(); err != nil {
-		return fmt.Errorf("failed to close tar writer: %w", err)
+// copyCodeToContainer copies the code and any project files to a directory
+// of their own in the container's workspace, so executions running at once
+// never share files
+func (s *Sandbox) copyCodeToContainer(ctx context.Context, tempDir string) error {
+	archive, err := projectArchive(tempDir, filepath.Base(tempDir))
+	if err != nil {
+		return err
 	}
+	return s.copyArchive(ctx, s.config.WorkingDir, archive)
+}
 
-	// Copy the tar archive to the container
-	err = s.client.CopyToContainer(ctx, "yz-sandbox", "/workspace", &buf, container.CopyToContainerOptions{
+// copyArchive extracts a tar archive into directory dir of the container.
+// Entries keep the owner and mode they have in the archive.
+func (s *Sandbox) copyArchive(ctx context.Context, dir string, archive io.Reader) error {
+	err := s.client.CopyToContainer(ctx, s.config.ContainerName, dir, archive, container.CopyToContainerOptions{
 		AllowOverwriteDirWithFile: true,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to copy code to container: %w", err)
+		return fmt.Errorf("failed to copy to container: %w", err)
 	}
 
 	return nil
 }
 
-// executeInContainer executes the Yz code in the container
-func (s *Sandbox) executeInContainer(ctx context.Context, containerID, tempDir string) (string, error) {
-	output, _, err := s.executeInContainerWithOptions(ctx, containerID, tempDir, false)
-	return output, err
+// projectDir returns the directory in the container an execution is built in
+func (s *Sandbox) projectDir(tempDir string) string {
+	return path.Join(s.config.WorkingDir, filepath.Base(tempDir))
 }
 
-// executeInContainerWithOptions executes the Yz code in the container with additional options
-func (s *Sandbox) executeInContainerWithOptions(ctx context.Context, containerID, tempDir string, showGeneratedCode bool) (string, string, error) {
+// caseDir returns the directory in the container a judged run's case inputs
+// are copied to
+func caseDir(tempDir string) string {
+	return casesDir + filepath.Base(tempDir)
+}
+
+// removeDir deletes a directory a run was given, such as its project
+// directory, from the container once the run is over, even if it was canceled
+func (s *Sandbox) removeDir(ctx context.Context, dir string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), killTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "docker", "exec", "-u", "root", s.config.ContainerName, "rm", "-rf", dir)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		logger.FromContext(ctx).Warn("Failed to remove directory", "dir", dir, "error", err,
+			"output", strings.TrimSpace(string(output)))
+	}
+}
+
+// executeInContainerWithOptions executes the Yz code in the container with additional options.
+// The returned run is never nil; on failure it carries the outcome and timings gathered so far.
+func (s *Sandbox) executeInContainerWithOptions(ctx context.Context, containerID, tempDir string, opts ExecutionOptions) (*containerRun, error) {
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = time.Duration(s.config.MaxExecutionTime) * time.Second
+	}
+	maxMemory := opts.MaxMemory
+	if maxMemory <= 0 {
+		maxMemory = s.config.MaxMemory
+	}
+	maxOutputSize := opts.MaxOutputSize
+	if maxOutputSize <= 0 {
+		maxOutputSize = s.config.MaxOutputSize
+	}
+
 	// Create execution context with timeout
-	execCtx, cancel := context.WithTimeout(ctx, time.Duration(s.config.MaxExecutionTime)*time.Second)
+	execCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	// Build the command based on whether we want to show generated code
-	var command string
-	if showGeneratedCode {
-		command = "cd /workspace && yzc -e main.yz"
-	} else {
-		command = "cd /workspace && yzc main.yz"
+	// Build the command based on whether we want to show generated code. A
+	// benchmark, a judged run, a profiled run or a multi-file project is built
+	// with yzc build and its program run after, and doesn't show generated code.
+	workDir := s.projectDir(tempDir)
+	compilerPath := opts.CompilerPath
+	if compilerPath == "" {
+		compilerPath = s.config.CompilerPath
+	}
+	compile := compilerPath + " " + MainFile
+	script := ""
+	if opts.CompileOnly {
+		compile = compilerPath + " build"
+	} else if opts.Runs > 0 {
+		script = benchmarkScript
+		compile = `bash -c "$` + scriptEnv + `" yz-benchmark ` + compilerPath + " " + strconv.Itoa(opts.Runs)
+	} else if len(opts.Cases) > 0 {
+		script = casesScript
+		compile = `bash -c "$` + scriptEnv + `" yz-cases ` + compilerPath + " " +
+			strconv.FormatFloat(opts.CaseTimeout.Seconds(), 'f', 3, 64) + " " + strconv.Itoa(maxOutputSize) + " " +
+			strconv.Itoa(len(opts.Cases)) + " " + caseDir(tempDir)
+	} else if opts.Profile {
+		script = profileScript
+		compile = `bash -c "$` + scriptEnv + `" yz-profile ` + compilerPath
+	} else if len(opts.Files) > 0 {
+		script = projectScript
+		compile = `bash -c "$` + scriptEnv + `" yz-project ` + compilerPath
+	} else if opts.ShowGeneratedCode {
+		compile = compilerPath + " -e " + MainFile
 	}
+	command := "cd " + workDir + " && " + withResourceStats(compile)
+
+	logger.FromContext(ctx).Debug("Running command in container", "container", containerID, "command", command)
 
 	// Use docker exec command directly
-	cmd := exec.CommandContext(execCtx, "docker", "exec", "-u", "yzuser", containerID,
-		"bash", "-c", command)
+	// The memory limit is only advisory: GOMEMLIMIT makes the Go runtime of the
+	// generated program collect garbage harder near it, but doesn't stop it going over.
+	// Canceling the context only kills the local docker client, so the run gets its
+	// own session in the container and is killed there once it is stopped.
+	// A judged run starts as root to read its case inputs; its script runs the
+	// build and the program as the sandbox user.
+	user := "yzuser"
+	if len(opts.Cases) > 0 {
+		user = "root"
+	}
+	pidFile := newPIDFile()
+	args := []string{"exec", "-u", user,
+		"-e", fmt.Sprintf("GOMEMLIMIT=%dB", maxMemory)}
+	if script != "" {
+		args = append(args, "-e", scriptEnv+"="+script)
+	}
+	args = append(args, containerID)
+	cmd := exec.CommandContext(execCtx, "docker", append(args, inSession(command, pidFile, timeout)...)...)
+
+	// Capture both stdout and stderr, noting when the compiler hands over to the
+	// program, and stop the run if it prints more than the limit
+	recorderLimit := maxOutputSize
+	if len(opts.Cases) > 0 {
+		// Each case's output arrives base64-encoded on its case line
+		recorderLimit += len(opts.Cases) * (base64.StdEncoding.EncodedLen(maxOutputSize+1) + 64)
+	}
+	recorder := newOutputRecorder(opts.Output, recorderLimit, cancel)
+	cmd.Stdout = recorder
+	cmd.Stderr = recorder
+
+	err := cmd.Run()
+	run := recorder.finish()
+	defer run.recordSpans(ctx)
+	if opts.Runs > 0 {
+		run.rawOutput, run.runs = extractBenchmarkRuns(run.rawOutput)
+	}
+	if len(opts.Cases) > 0 {
+		run.rawOutput, run.cases = extractCaseRuns(run.rawOutput, len(opts.Cases), opts.CaseTimeout, maxOutputSize)
+	}
+
+	// The run was stopped by its timeout, the output limit or the caller
+	if execCtx.Err() != nil {
+		killCtx, cancelKill := context.WithTimeout(context.WithoutCancel(ctx), killTimeout)
+		if err := s.killSession(killCtx, containerID, pidFile); err != nil {
+			logger.FromContext(ctx).Error("Failed to kill execution processes", "error", err)
+		}
+		cancelKill()
+	}
+
+	if run.truncated {
+		run.outcome = OutcomeOutputLimit
+		programOutput, generatedCode := parseCompilerOutput(run.rawOutput, opts.ShowGeneratedCode)
+		run.output = strings.TrimSpace(programOutput) + "\n" + fmt.Sprintf(truncationMarker, maxOutputSize)
+		run.generatedCode = strings.TrimSpace(generatedCode)
+		return run, fmt.Errorf("output exceeded the %d byte limit", maxOutputSize)
+	}
 
-	// Use CombinedOutput to capture both stdout and stderr
-	output, err := cmd.CombinedOutput()
 	if err != nil {
+		run.outcome = classifyFailure(execCtx, err, run)
 		if exitError, ok := err.(*exec.ExitError); ok {
 			// Return the full combined output which includes compilation errors
-			errorOutput := string(output)
+			errorOutput := run.rawOutput
 			if errorOutput == "" {
 				errorOutput = string(exitError.Stderr)
 			}
-			return "", "", fmt.Errorf("execution failed with exit code %d:\n%s", exitError.ExitCode(), errorOutput)
+			if run.outcome == OutcomeTimeout {
+				return run, fmt.Errorf("execution timed out after %v:\n%s", timeout, errorOutput)
+			}
+			return run, fmt.Errorf("execution failed with exit code %d:\n%s", exitError.ExitCode(), errorOutput)
+		}
+		if run.outcome == OutcomeTimeout {
+			return run, fmt.Errorf("execution timed out after %v", timeout)
 		}
-		return "", "", fmt.Errorf("failed to execute command: %w", err)
+		return run, fmt.Errorf("failed to execute command: %w", err)
 	}
+	run.outcome = OutcomeSuccess
 
-	// Parse output to separate program output from generated code
-	programOutput, generatedCode := parseCompilerOutput(string(output), showGeneratedCode)
-
-	return strings.TrimSpace(programOutput), strings.TrimSpace(generatedCode), nil
-}
-
-// removeContainer removes the Docker container
-func (s *Sandbox) removeContainer(ctx context.Context, containerID string) {
-	options := container.RemoveOptions{
-		Force: true,
+	// A build prints only compiler messages
+	if opts.CompileOnly {
+		run.output = strings.TrimSpace(run.rawOutput)
+		return run, nil
 	}
 
-	if err := s.client.ContainerRemove(ctx, containerID, options); err != nil {
-		fmt.Printf("Warning: failed to remove container %s: %v\n", containerID, err)
-	}
+	// Parse output to separate program output from generated code
+	_, parseSpan := tracing.Start(ctx, "sandbox.parse_output",
+		trace.WithAttributes(attribute.Int("output_size", len(run.rawOutput))))
+	programOutput, generatedCode := parseCompilerOutput(run.rawOutput, opts.ShowGeneratedCode)
+	parseSpan.End()
+	run.output = strings.TrimSpace(programOutput)
+	run.generatedCode = strings.TrimSpace(generatedCode)
+
+	return run, nil
 }
 
 // Close closes the sandbox client
@@ -359,9 +625,3 @@ func parseCompilerOutput(output string, showGeneratedCode bool) (string, string)
 
 	return programResult, generatedResult
 }
-
-// filterCompilerOutput cleans up any remaining binary data from Docker exec
-func filterCompilerOutput(output string) string {
-	programOutput, _ := parseCompilerOutput(output, false)
-	return programOutput
-}
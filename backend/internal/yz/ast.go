@@ -0,0 +1,87 @@
+package yz
+
+import "fmt"
+
+// NodeKind is the kind of a syntax tree node
+type NodeKind int
+
+// Node kinds. The comment after each kind says what its Text and Children are.
+const (
+	NodeProgram     NodeKind = iota // children: statements
+	NodeBlock                       // children: statements
+	NodeSignature                   // #(...) children: parameters, then the body block if any
+	NodeParam                       // text: name, empty for an unnamed result type; children: type
+	NodeDeclaration                 // text: name; children: type, value, or type then value
+	NodeAssignment                  // text: operator; children: target, value
+	NodeJump                        // text: return, break or continue; children: value if any
+	NodeConditional                 // cond ? then else; children: condition, then, else if any
+	NodeBinary                      // text: operator; children: left, right
+	NodeUnary                       // text: operator; children: operand
+	NodeCall                        // children: callee, then arguments
+	NodeArgument                    // text: name; children: value. Only named arguments have a node
+	NodeIndex                       // children: value, index
+	NodeMember                      // text: member name; children: value
+	NodeGroup                       // (...) children: expression
+	NodeList                        // [...] children: elements or pairs
+	NodePair                        // key : value in a list; children: key, value
+	NodeIdent                       // text: name
+	NodeNumber                      // text: literal
+	NodeString                      // text: literal; children: interpolated expressions
+)
+
+// nodeKindNames are the names of node kinds, as used in JSON
+var nodeKindNames = [...]string{
+	NodeProgram:     "program",
+	NodeBlock:       "block",
+	NodeSignature:   "signature",
+	NodeParam:       "param",
+	NodeDeclaration: "declaration",
+	NodeAssignment:  "assignment",
+	NodeJump:        "jump",
+	NodeConditional: "conditional",
+	NodeBinary:      "binary",
+	NodeUnary:       "unary",
+	NodeCall:        "call",
+	NodeArgument:    "argument",
+	NodeIndex:       "index",
+	NodeMember:      "member",
+	NodeGroup:       "group",
+	NodeList:        "list",
+	NodePair:        "pair",
+	NodeIdent:       "ident",
+	NodeNumber:      "number",
+	NodeString:      "string",
+}
+
+// String returns the name of the kind
+func (k NodeKind) String() string {
+	if k >= 0 && int(k) < len(nodeKindNames) {
+		return nodeKindNames[k]
+	}
+	return fmt.Sprintf("node(%d)", int(k))
+}
+
+// Node is a node of a Yz syntax tree
+type Node struct {
+	Kind     NodeKind
+	Text     string // name, operator or literal, depending on the kind
+	Span     Span
+	Children []*Node
+}
+
+// File is parsed Yz source
+type File struct {
+	Tokens []Token // every token, including comments and newlines, ending with EOF
+	Root   *Node   // a NodeProgram
+}
+
+// Walk calls visit for n and its descendants in depth-first order, skipping
+// the children of nodes for which visit returns false
+func Walk(n *Node, visit func(*Node) bool) {
+	if !visit(n) {
+		return
+	}
+	for _, child := range n.Children {
+		Walk(child, visit)
+	}
+}
@@ -0,0 +1,217 @@
+package yz
+
+import (
+	"fmt"
+	"strings"
+)
+
+// indentUnit is the indentation of each nesting level
+const indentUnit = "    "
+
+// closingText is the source text of each closing bracket
+var closingText = map[Kind]string{RBrace: "}", RParen: ")", RBracket: "]"}
+
+// Format returns src in canonical Yz style. Lines are indented four spaces per
+// open bracket, spacing around operators, punctuation and brackets is
+// normalized, trailing whitespace is removed and runs of blank lines are
+// collapsed to one. Line breaks stay where they are, since they end
+// statements. Source that can't be tokenized or whose brackets don't balance
+// returns a *SyntaxError.
+func Format(src string) (string, error) {
+	tokens, err := tokenizeBalanced(src)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	var open []bracket
+	blank := false // whether a blank line is pending before the next line
+	var line []Token
+	for _, tok := range tokens {
+		if tok.Kind != Newline && tok.Kind != EOF {
+			line = append(line, tok)
+			continue
+		}
+
+		if len(line) == 0 {
+			blank = out.Len() > 0
+			continue
+		}
+		if blank {
+			out.WriteByte('\n')
+			blank = false
+		}
+		open = formatLine(&out, line, open)
+		out.WriteByte('\n')
+		line = line[:0]
+	}
+	return out.String(), nil
+}
+
+// bracket is a bracket left open at the end of a line
+type bracket struct {
+	kind  Kind
+	level int // indentation level of the line it was opened on
+}
+
+// formatLine writes one line of tokens, indented for the brackets open before
+// it, and returns the brackets open after it
+func formatLine(out *strings.Builder, line []Token, open []bracket) []bracket {
+	level := 0
+	if n := len(open); n > 0 {
+		// A line starting with a closing bracket lines up with the line that opened it
+		level = open[n-1].level
+		if !line[0].Kind.Closes() {
+			level++
+		}
+	}
+	out.WriteString(strings.Repeat(indentUnit, level))
+
+	var glued []Token // tokens written since the last space
+	for i, tok := range line {
+		if i > 0 {
+			inBrackets := len(open) > 0 && open[len(open)-1].kind != LBrace
+			sep := space(line, i, inBrackets)
+			if sep == "" && !lexesApart(append(glued, tok)) {
+				// Joined up they would read as other tokens, such as + and == as += =
+				sep = " "
+			}
+			if sep != "" {
+				glued = glued[:0]
+			}
+			out.WriteString(sep)
+		}
+		glued = append(glued, tok)
+		out.WriteString(tok.Text)
+
+		switch {
+		case tok.Kind.Opens():
+			open = append(open, bracket{kind: tok.Kind, level: level})
+		case tok.Kind.Closes():
+			open = open[:len(open)-1]
+		}
+	}
+	return open
+}
+
+// space returns the whitespace between line[i-1] and line[i]. inBrackets
+// reports whether they are inside parentheses or square brackets rather than
+// a block. Where no rule applies, whitespace in the source is kept as a
+// single space, since adjacency can be significant, e.g. names[0] indexes
+// but names [String] declares a list.
+func space(line []Token, i int, inBrackets bool) string {
+	a, b := line[i-1], line[i]
+	switch {
+	case a.Kind == Comment || b.Kind == Comment:
+		return " "
+	case a.Kind == LParen || a.Kind == LBracket || b.Kind == RParen || b.Kind == RBracket:
+		return ""
+	case b.Kind == Comma || b.Kind == Semicolon:
+		return ""
+	case a.Kind == Comma || a.Kind == Semicolon:
+		return " "
+	case a.Kind == Dot || b.Kind == Dot:
+		return ""
+	case a.Kind == LBrace && b.Kind == RBrace:
+		return ""
+	case a.Kind == LBrace || b.Kind == LBrace || b.Kind == RBrace:
+		return " "
+	case b.Kind == Colon:
+		// Keys in lists and arguments read as key: value, declarations as name : value
+		if inBrackets {
+			return ""
+		}
+		return " "
+	case a.Kind == Colon:
+		return " "
+	case a.Kind == Hash:
+		return ""
+	case a.Kind == Operator && isUnary(line, i-1):
+		return ""
+	case a.Kind == Operator || b.Kind == Operator:
+		return " "
+	case a.End.Offset == b.Pos.Offset:
+		return ""
+	}
+	return " "
+}
+
+// lexesApart reports whether the text of tokens written without spaces reads
+// back as the same tokens
+func lexesApart(tokens []Token) bool {
+	var text strings.Builder
+	for _, tok := range tokens {
+		text.WriteString(tok.Text)
+	}
+	relexed, err := Tokenize(text.String())
+	if err != nil || len(relexed) != len(tokens)+1 {
+		return false
+	}
+	for i, tok := range tokens {
+		if relexed[i].Kind != tok.Kind || relexed[i].Text != tok.Text {
+			return false
+		}
+	}
+	return true
+}
+
+// isUnary reports whether the operator at line[i] applies to the operand after it
+func isUnary(line []Token, i int) bool {
+	switch line[i].Text {
+	case "-", "+", "!", "~":
+	default:
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	switch line[i-1].Kind {
+	case Operator, LParen, LBracket, LBrace, Comma, Semicolon, Colon:
+		return true
+	}
+	return false
+}
+
+// Check returns a *SyntaxError if src can't be tokenized or its brackets don't balance
+func Check(src string) error {
+	_, err := tokenizeBalanced(src)
+	return err
+}
+
+// tokenizeBalanced tokenizes src and checks that its brackets balance
+func tokenizeBalanced(src string) ([]Token, error) {
+	tokens, err := Tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkBrackets(tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// checkBrackets returns a *SyntaxError for the first bracket that isn't matched
+func checkBrackets(tokens []Token) error {
+	var open []Token
+	for _, tok := range tokens {
+		switch {
+		case tok.Kind.Opens():
+			open = append(open, tok)
+		case tok.Kind.Closes():
+			if len(open) == 0 {
+				return &SyntaxError{Pos: tok.Pos, Msg: fmt.Sprintf("unexpected %s", tok.Text)}
+			}
+			last := open[len(open)-1]
+			if last.Kind.closer() != tok.Kind {
+				return &SyntaxError{Pos: tok.Pos, Msg: fmt.Sprintf("unexpected %s, expected %s to close %s at %s",
+					tok.Text, closingText[last.Kind.closer()], last.Text, last.Pos)}
+			}
+			open = open[:len(open)-1]
+		}
+	}
+	if len(open) > 0 {
+		last := open[len(open)-1]
+		return &SyntaxError{Pos: last.Pos, Msg: fmt.Sprintf("unclosed %s", last.Text)}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package yz
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// formatTests pairs source with its canonical form
+var formatTests = []struct {
+	name string
+	src  string
+	want string
+}{
+	{"operators", "a:1+2*3", "a : 1 + 2 * 3\n"},
+	{"block", "main: {\nprintln( \"hi\" )\n}", "main : {\n    println(\"hi\")\n}\n"},
+	{"arguments", "f(a,b ,c)", "f(a, b, c)\n"},
+	{"list type keeps its space", "names [String]", "names [String]\n"},
+	{"index stays joined", "names[0]", "names[0]\n"},
+	{"unary minus", "x : -1", "x : -1\n"},
+	{"binary then unary minus", "a - -b", "a - -b\n"},
+	{"not", "x=!y", "x = !y\n"},
+	{"blank lines collapse", "if x>0 {\n\n\n  y\n}  ", "if x > 0 {\n\n    y\n}\n"},
+	{"leading blank lines", "\n\nx", "x\n"},
+	{"block literal", "{ a: 1 , b : 2 }", "{ a : 1, b : 2 }\n"},
+	{"empty block", "f : { }", "f : {}\n"},
+	{"list literal", "list : [1,2, 3]", "list : [1, 2, 3]\n"},
+	{"keys in arguments", "p : (a:1, b:2)", "p : (a: 1, b: 2)\n"},
+	{"statements on a line", "a  = b;c=d", "a = b; c = d\n"},
+	{"comments", "c: {\n// hi\n  x   // trailing\n}", "c : {\n    // hi\n    x // trailing\n}\n"},
+	{"method call on a number", "1.5.abs()", "1.5.abs()\n"},
+	{"open bracket across lines", "(\n1,\n2\n)", "(\n    1,\n    2\n)\n"},
+	{"unary plus before an operator", "x==+ ==y", "x == + == y\n"},
+	{"plus-assign stays apart from =", "x == +== y", "x == += = y\n"},
+	{"exponent without digits", "x : 1e+ 2", "x : 1e + 2\n"},
+	{"number, dot and number", "1 . 5", "1. 5\n"},
+}
+
+func TestFormat(t *testing.T) {
+	for _, tt := range formatTests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Format(tt.src)
+			if err != nil {
+				t.Fatalf("Format(%q): %v", tt.src, err)
+			}
+			if got != tt.want {
+				t.Errorf("Format(%q)\n got %q\nwant %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatIdempotent(t *testing.T) {
+	sources := []string{"==+ ==", "a+ +b", "a- -b", "x : - -1", "a&& &b", "a| |b", "x =>> y", "a- >b", "1e+", "x<= =y"}
+	for _, tt := range formatTests {
+		sources = append(sources, tt.src)
+	}
+	for _, src := range sources {
+		once, err := Format(src)
+		if err != nil {
+			t.Errorf("Format(%q): %v", src, err)
+			continue
+		}
+		twice, err := Format(once)
+		if err != nil {
+			t.Errorf("Format(%q) = %q, which doesn't format: %v", src, once, err)
+			continue
+		}
+		if twice != once {
+			t.Errorf("Format(%q) = %q, but formatting that gives %q", src, once, twice)
+		}
+	}
+}
+
+func TestFormatKeepsTokens(t *testing.T) {
+	for _, tt := range formatTests {
+		before, _ := Tokenize(tt.src)
+		after, _ := Tokenize(tt.want)
+		if got, want := meaningful(after), meaningful(before); !slices.Equal(got, want) {
+			t.Errorf("Format(%q) changed the tokens\n got %v\nwant %v", tt.src, got, want)
+		}
+	}
+}
+
+// meaningful returns the kind and text of tokens, leaving out newlines
+func meaningful(tokens []Token) []string {
+	var out []string
+	for _, tok := range tokens {
+		if tok.Kind != Newline {
+			out = append(out, tok.Kind.String()+":"+tok.Text)
+		}
+	}
+	return out
+}
+
+func TestFormatErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		pos Pos
+		msg string
+	}{
+		{"{", Pos{Line: 1, Column: 1, Offset: 0}, "unclosed {"},
+		{"}", Pos{Line: 1, Column: 1, Offset: 0}, "unexpected }"},
+		{"(]", Pos{Line: 1, Column: 2, Offset: 1}, "unexpected ], expected ) to close ( at 1:1"},
+		{"a {\n (\n}", Pos{Line: 3, Column: 1, Offset: 7}, "unexpected }, expected ) to close ( at 2:2"},
+		{`x : "open`, Pos{Line: 1, Column: 5, Offset: 4}, "unterminated string"},
+	}
+	for _, tt := range tests {
+		_, err := Format(tt.src)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("Format(%q) error = %v, want a *SyntaxError", tt.src, err)
+			continue
+		}
+		if syntaxErr.Pos != tt.pos || syntaxErr.Msg != tt.msg {
+			t.Errorf("Format(%q) error = %q at %+v, want %q at %+v", tt.src, syntaxErr.Msg, syntaxErr.Pos, tt.msg, tt.pos)
+		}
+	}
+}
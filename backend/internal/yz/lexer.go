@@ -0,0 +1,336 @@
+package yz
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// operators lists the multi-character operators; any other operator is a single character
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "+=", "-=", "*=", "/=", "%=", "=>", "->"}
+
+// operatorChars are the characters operators are made of
+const operatorChars = "+-*/%=<>!&|^~?"
+
+// punctuation maps single-character tokens to their kinds
+var punctuation = map[rune]Kind{
+	'{': LBrace,
+	'}': RBrace,
+	'(': LParen,
+	')': RParen,
+	'[': LBracket,
+	']': RBracket,
+	',': Comma,
+	';': Semicolon,
+	':': Colon,
+	'.': Dot,
+	'#': Hash,
+}
+
+// Tokenize splits src into tokens, ending with an EOF token. Newlines and
+// comments are kept as tokens since newlines end statements in Yz. On error
+// the tokens read so far are returned along with a *SyntaxError.
+func Tokenize(src string) ([]Token, error) {
+	l := &lexer{src: src, pos: Pos{Line: 1, Column: 1}}
+	var tokens []Token
+	for {
+		tok, err := l.next()
+		if err != nil {
+			return tokens, err
+		}
+		tokens = append(tokens, tok)
+		if tok.Kind == EOF {
+			return tokens, nil
+		}
+	}
+}
+
+// Interpolated returns the tokens of the expressions interpolated in a string
+// token, such as x + y in "sum: `x + y`", placed where they are in the source
+func Interpolated(tok Token) []Token {
+	var tokens []Token
+	for _, expr := range interpolations(tok) {
+		tokens = append(tokens, expr...)
+	}
+	return tokens
+}
+
+// interpolations returns the tokens of each expression interpolated in a
+// string token, without EOF tokens
+func interpolations(tok Token) [][]Token {
+	if tok.Kind != String || strings.HasPrefix(tok.Text, "`") || !strings.ContainsRune(tok.Text, '`') {
+		return nil
+	}
+
+	var exprs [][]Token
+	l := &lexer{src: tok.Text, pos: Pos{Line: 1, Column: 1}}
+	l.advance()
+	for r := l.peek(); r != -1; r = l.peek() {
+		switch r {
+		case '\\':
+			l.advance()
+		case '`':
+			l.advance()
+			start := l.pos
+			l.interpolation()
+			inner, _ := Tokenize(tok.Text[start.Offset : l.pos.Offset-1])
+			base := start.shift(tok.Pos)
+			var expr []Token
+			for _, t := range inner {
+				if t.Kind != EOF {
+					t.Pos, t.End = t.Pos.shift(base), t.End.shift(base)
+					expr = append(expr, t)
+				}
+			}
+			exprs = append(exprs, expr)
+			continue
+		}
+		l.advance()
+	}
+	return exprs
+}
+
+// shift converts a position relative to base, where base is at line 1, column 1,
+// into an absolute one
+func (p Pos) shift(base Pos) Pos {
+	if p.Line == 1 {
+		p.Column += base.Column - 1
+	}
+	p.Line += base.Line - 1
+	p.Offset += base.Offset
+	return p
+}
+
+// lexer reads tokens from source code
+type lexer struct {
+	src string
+	pos Pos
+}
+
+// peek returns the character at the current position, or -1 at the end
+func (l *lexer) peek() rune {
+	if l.pos.Offset >= len(l.src) {
+		return -1
+	}
+	r, _ := utf8.DecodeRuneInString(l.src[l.pos.Offset:])
+	return r
+}
+
+// peekNext returns the character after the current one, or -1 past the end
+func (l *lexer) peekNext() rune {
+	if l.pos.Offset >= len(l.src) {
+		return -1
+	}
+	_, size := utf8.DecodeRuneInString(l.src[l.pos.Offset:])
+	if l.pos.Offset+size >= len(l.src) {
+		return -1
+	}
+	r, _ := utf8.DecodeRuneInString(l.src[l.pos.Offset+size:])
+	return r
+}
+
+// advance moves past the current character
+func (l *lexer) advance() {
+	r, size := utf8.DecodeRuneInString(l.src[l.pos.Offset:])
+	l.pos.Offset += size
+	if r == '\n' {
+		l.pos.Line++
+		l.pos.Column = 1
+	} else {
+		l.pos.Column++
+	}
+}
+
+// next reads the next token
+func (l *lexer) next() (Token, error) {
+	for r := l.peek(); r == ' ' || r == '\t' || r == '\r' || r == '\f'; r = l.peek() {
+		l.advance()
+	}
+
+	start := l.pos
+	kind, err := l.scan()
+	if err != nil {
+		return Token{}, err
+	}
+	return Token{Kind: kind, Text: l.src[start.Offset:l.pos.Offset], Pos: start, End: l.pos}, nil
+}
+
+// scan moves past the token at the current position and returns its kind
+func (l *lexer) scan() (Kind, error) {
+	start := l.pos
+	r := l.peek()
+	switch {
+	case r == -1:
+		return EOF, nil
+	case r == '\n':
+		l.advance()
+		return Newline, nil
+	case r == '/' && l.peekNext() == '/':
+		for r := l.peek(); r != -1 && r != '\n'; r = l.peek() {
+			l.advance()
+		}
+		return Comment, nil
+	case r == '/' && l.peekNext() == '*':
+		l.advance()
+		l.advance()
+		for !strings.HasPrefix(l.src[l.pos.Offset:], "*/") {
+			if l.peek() == -1 {
+				return Illegal, &SyntaxError{Pos: start, Msg: "unterminated comment"}
+			}
+			l.advance()
+		}
+		l.advance()
+		l.advance()
+		return Comment, nil
+	case r == '"' || r == '\'':
+		if !l.quoted(r) {
+			return Illegal, &SyntaxError{Pos: start, Msg: "unterminated string"}
+		}
+		return String, nil
+	case r == '`':
+		l.advance()
+		if !l.skipPast('`') {
+			return Illegal, &SyntaxError{Pos: start, Msg: "unterminated raw string"}
+		}
+		return String, nil
+	case isDigit(r):
+		l.number()
+		return Number, nil
+	case isLetter(r):
+		for r := l.peek(); isLetter(r) || isDigit(r); r = l.peek() {
+			l.advance()
+		}
+		return Ident, nil
+	}
+
+	if kind, ok := punctuation[r]; ok {
+		l.advance()
+		return kind, nil
+	}
+	if strings.ContainsRune(operatorChars, r) {
+		for _, op := range operators {
+			if strings.HasPrefix(l.src[l.pos.Offset:], op) {
+				l.advance()
+				l.advance()
+				return Operator, nil
+			}
+		}
+		l.advance()
+		return Operator, nil
+	}
+	return Illegal, &SyntaxError{Pos: start, Msg: fmt.Sprintf("unexpected character %q", r)}
+}
+
+// quoted moves past a string delimited by quote, including any `interpolated`
+// expressions, which may contain strings themselves. It reports whether the
+// string was terminated.
+func (l *lexer) quoted(quote rune) bool {
+	l.advance()
+	for {
+		switch l.peek() {
+		case -1:
+			return false
+		case '\\':
+			l.advance()
+			if l.peek() == -1 {
+				return false
+			}
+		case quote:
+			l.advance()
+			return true
+		case '`':
+			l.advance()
+			if !l.interpolation() {
+				return false
+			}
+			continue
+		}
+		l.advance()
+	}
+}
+
+// interpolation moves past an interpolated expression up to its closing backtick
+func (l *lexer) interpolation() bool {
+	for {
+		switch r := l.peek(); r {
+		case -1:
+			return false
+		case '`':
+			l.advance()
+			return true
+		case '"', '\'':
+			if !l.quoted(r) {
+				return false
+			}
+		default:
+			l.advance()
+		}
+	}
+}
+
+// skipPast moves past the next occurrence of r, reporting whether there was one
+func (l *lexer) skipPast(r rune) bool {
+	for {
+		switch l.peek() {
+		case -1:
+			return false
+		case r:
+			l.advance()
+			return true
+		}
+		l.advance()
+	}
+}
+
+// number moves past a number literal
+func (l *lexer) number() {
+	if l.peek() == '0' && strings.ContainsRune("xXbBoO", l.peekNext()) {
+		l.advance()
+		l.advance()
+		for r := l.peek(); isLetter(r) || isDigit(r); r = l.peek() {
+			l.advance()
+		}
+		return
+	}
+
+	l.digits()
+	if l.peek() == '.' && isDigit(l.peekNext()) {
+		l.advance()
+		l.digits()
+	}
+	if r := l.peek(); (r == 'e' || r == 'E') && isExponent(l.src[l.pos.Offset+1:]) {
+		l.advance()
+		if r := l.peek(); r == '+' || r == '-' {
+			l.advance()
+		}
+		l.digits()
+	}
+}
+
+// isExponent reports whether rest, the source after an e in a number, starts
+// with the digits of an exponent, with an optional sign. 1e+ is the number 1
+// followed by e+.
+func isExponent(rest string) bool {
+	if strings.HasPrefix(rest, "+") || strings.HasPrefix(rest, "-") {
+		rest = rest[1:]
+	}
+	return rest != "" && isDigit(rune(rest[0]))
+}
+
+// digits moves past decimal digits and underscores
+func (l *lexer) digits() {
+	for r := l.peek(); isDigit(r) || r == '_'; r = l.peek() {
+		l.advance()
+	}
+}
+
+// isLetter reports whether r can start a name
+func isLetter(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+// isDigit reports whether r is a decimal digit
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
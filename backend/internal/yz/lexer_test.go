@@ -0,0 +1,102 @@
+package yz
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string // kind:text of each token before EOF
+	}{
+		{"name and number", "x := 42", []string{"ident:x", "colon::", "operator:=", "number:42"}},
+		{"multi-character operators", "a == b != c <= d >= e && f || g += h -= i => j -> k",
+			[]string{"ident:a", "operator:==", "ident:b", "operator:!=", "ident:c", "operator:<=", "ident:d",
+				"operator:>=", "ident:e", "operator:&&", "ident:f", "operator:||", "ident:g", "operator:+=",
+				"ident:h", "operator:-=", "ident:i", "operator:=>", "ident:j", "operator:->", "ident:k"}},
+		{"operators that don't join", "a--b", []string{"ident:a", "operator:-", "operator:-", "ident:b"}},
+		{"decimal", "3.14", []string{"number:3.14"}},
+		{"method call on a number", "1.abs()", []string{"number:1", "dot:.", "ident:abs", "lparen:(", "rparen:)"}},
+		{"exponent", "1e10 2.5E-3 6e+2", []string{"number:1e10", "number:2.5E-3", "number:6e+2"}},
+		{"exponent without digits", "1e+", []string{"number:1", "ident:e", "operator:+"}},
+		{"exponent sign without digits", "1e-x", []string{"number:1", "ident:e", "operator:-", "ident:x"}},
+		{"hex and binary", "0xFF 0b101", []string{"number:0xFF", "number:0b101"}},
+		{"underscores", "1_000_000", []string{"number:1_000_000"}},
+		{"strings", `"a" 'b' ` + "`c`", []string{`string:"a"`, "string:'b'", "string:`c`"}},
+		{"escaped quote", `"a\"b"`, []string{`string:"a\"b"`}},
+		{"interpolation", "\"sum: `x + \"y\"`\"", []string{"string:\"sum: `x + \"y\"`\""}},
+		{"comments", "x // line\n/* block\n*/ y", []string{"ident:x", "comment:// line", "newline:\n",
+			"comment:/* block\n*/", "ident:y"}},
+		{"brackets and punctuation", "{ ( [ ] ) } , ; : . #", []string{"lbrace:{", "lparen:(", "lbracket:[",
+			"rbracket:]", "rparen:)", "rbrace:}", "comma:,", "semicolon:;", "colon::", "dot:.", "hash:#"}},
+		{"unicode name", "año := 1", []string{"ident:año", "colon::", "operator:=", "number:1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := Tokenize(tt.src)
+			if err != nil {
+				t.Fatalf("Tokenize(%q): %v", tt.src, err)
+			}
+			if last := tokens[len(tokens)-1]; last.Kind != EOF {
+				t.Fatalf("Tokenize(%q) ends with %s, want eof", tt.src, last.Kind)
+			}
+			var got []string
+			for _, tok := range tokens[:len(tokens)-1] {
+				got = append(got, tok.Kind.String()+":"+tok.Text)
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("Tokenize(%q)\n got %q\nwant %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenizePositions(t *testing.T) {
+	tokens, err := Tokenize("a := 1\n  bb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		text     string
+		pos, end Pos
+	}{
+		{"a", Pos{Line: 1, Column: 1, Offset: 0}, Pos{Line: 1, Column: 2, Offset: 1}},
+		{":", Pos{Line: 1, Column: 3, Offset: 2}, Pos{Line: 1, Column: 4, Offset: 3}},
+		{"=", Pos{Line: 1, Column: 4, Offset: 3}, Pos{Line: 1, Column: 5, Offset: 4}},
+		{"1", Pos{Line: 1, Column: 6, Offset: 5}, Pos{Line: 1, Column: 7, Offset: 6}},
+		{"\n", Pos{Line: 1, Column: 7, Offset: 6}, Pos{Line: 2, Column: 1, Offset: 7}},
+		{"bb", Pos{Line: 2, Column: 3, Offset: 9}, Pos{Line: 2, Column: 5, Offset: 11}},
+	}
+	for i, w := range want {
+		if tok := tokens[i]; tok.Text != w.text || tok.Pos != w.pos || tok.End != w.end {
+			t.Errorf("token %d = %q at %+v-%+v, want %q at %+v-%+v", i, tok.Text, tok.Pos, tok.End, w.text, w.pos, w.end)
+		}
+	}
+}
+
+func TestTokenizeErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		pos Pos
+		msg string
+	}{
+		{`x := "abc`, Pos{Line: 1, Column: 6, Offset: 5}, "unterminated string"},
+		{"x\n/* open", Pos{Line: 2, Column: 1, Offset: 2}, "unterminated comment"},
+		{"`raw", Pos{Line: 1, Column: 1, Offset: 0}, "unterminated raw string"},
+		{"a $ b", Pos{Line: 1, Column: 3, Offset: 2}, `unexpected character '$'`},
+	}
+	for _, tt := range tests {
+		_, err := Tokenize(tt.src)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("Tokenize(%q) error = %v, want a *SyntaxError", tt.src, err)
+			continue
+		}
+		if syntaxErr.Pos != tt.pos || syntaxErr.Msg != tt.msg {
+			t.Errorf("Tokenize(%q) error = %q at %+v, want %q at %+v", tt.src, syntaxErr.Msg, syntaxErr.Pos, tt.msg, tt.pos)
+		}
+	}
+}
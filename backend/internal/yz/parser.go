@@ -0,0 +1,518 @@
+package yz
+
+import "fmt"
+
+// precedence gives the binding power of binary operators; higher binds tighter
+var precedence = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"==": 3, "!=": 3,
+	"<": 4, "<=": 4, ">": 4, ">=": 4,
+	"+": 5, "-": 5, "|": 5, "^": 5,
+	"*": 6, "/": 6, "%": 6, "&": 6,
+}
+
+// unaryOperators are the operators that may prefix an operand
+var unaryOperators = map[string]bool{"-": true, "+": true, "!": true, "~": true}
+
+// assignmentOperators are the operators that assign to their left side
+var assignmentOperators = map[string]bool{"=": true, "+=": true, "-=": true, "*=": true, "/=": true, "%=": true}
+
+// jumps are the statements that leave a block early
+var jumps = map[string]bool{"return": true, "break": true, "continue": true}
+
+// Parse tokenizes src and parses it into a syntax tree. Source that can't be
+// parsed returns a *SyntaxError, along with the tokens read before it.
+func Parse(src string) (*File, error) {
+	tokens, err := Tokenize(src)
+	file := &File{Tokens: tokens}
+	if err != nil {
+		return file, err
+	}
+	if err := checkBrackets(tokens); err != nil {
+		return file, err
+	}
+
+	p := newParser(tokens)
+	if file.Root, err = p.program(); err != nil {
+		return file, err
+	}
+	return file, nil
+}
+
+// parser builds a syntax tree from tokens. Newlines end statements, so they
+// are skipped only where an expression can't end, as after an operator or
+// inside parentheses.
+type parser struct {
+	tokens []Token // without comments, ending with EOF
+	i      int
+}
+
+// newParser returns a parser for tokens, which must end with EOF
+func newParser(tokens []Token) *parser {
+	p := &parser{}
+	for _, tok := range tokens {
+		if tok.Kind != Comment {
+			p.tokens = append(p.tokens, tok)
+		}
+	}
+	return p
+}
+
+// peek returns the current token
+func (p *parser) peek() Token {
+	return p.tokens[p.i]
+}
+
+// peekNext returns the token after the current one
+func (p *parser) peekNext() Token {
+	return p.tokens[min(p.i+1, len(p.tokens)-1)]
+}
+
+// next moves past the current token and returns it; EOF is never passed
+func (p *parser) next() Token {
+	tok := p.tokens[p.i]
+	if tok.Kind != EOF {
+		p.i++
+	}
+	return tok
+}
+
+// skipNewlines moves past any newlines
+func (p *parser) skipNewlines() {
+	for p.peek().Kind == Newline {
+		p.next()
+	}
+}
+
+// expect moves past the current token if it has the given kind, and returns
+// a *SyntaxError naming what was expected otherwise
+func (p *parser) expect(kind Kind, what string) (Token, error) {
+	tok := p.peek()
+	if tok.Kind != kind {
+		return tok, p.errorf(tok, "expected %s, found %s", what, describe(tok))
+	}
+	return p.next(), nil
+}
+
+// errorf returns a *SyntaxError at tok
+func (p *parser) errorf(tok Token, format string, args ...any) error {
+	return &SyntaxError{Pos: tok.Pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// describe names a token in error messages
+func describe(tok Token) string {
+	switch tok.Kind {
+	case Newline:
+		return "newline"
+	case EOF:
+		return "end of input"
+	}
+	return tok.Text
+}
+
+// program parses the statements of a whole source file
+func (p *parser) program() (*Node, error) {
+	stmts, err := p.statements(EOF)
+	if err != nil {
+		return nil, err
+	}
+	return &Node{Kind: NodeProgram, Span: Span{Start: Pos{Line: 1, Column: 1}, End: p.peek().End}, Children: stmts}, nil
+}
+
+// statements parses statements separated by newlines or semicolons, up to a
+// token of the end kind
+func (p *parser) statements(end Kind) ([]*Node, error) {
+	var stmts []*Node
+	for {
+		for kind := p.peek().Kind; kind == Newline || kind == Semicolon; kind = p.peek().Kind {
+			p.next()
+		}
+		if p.peek().Kind == end {
+			return stmts, nil
+		}
+
+		stmt, err := p.statement()
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, stmt)
+		if tok := p.peek(); tok.Kind != Newline && tok.Kind != Semicolon && tok.Kind != end {
+			return nil, p.errorf(tok, "expected newline or ; after statement, found %s", describe(tok))
+		}
+	}
+}
+
+// statement parses a declaration, an assignment, a jump or an expression
+func (p *parser) statement() (*Node, error) {
+	tok, next := p.peek(), p.peekNext()
+	switch {
+	case tok.Kind == Ident && jumps[tok.Text]:
+		p.next()
+		n := &Node{Kind: NodeJump, Text: tok.Text, Span: Span{Start: tok.Pos, End: tok.End}}
+		if kind := p.peek().Kind; kind != Newline && kind != Semicolon && kind != RBrace && kind != EOF {
+			value, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			n.Children = []*Node{value}
+			n.Span.End = value.Span.End
+		}
+		return n, nil
+
+	case tok.Kind == Ident && next.Kind == Colon:
+		p.next()
+		p.next()
+		value, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		return &Node{Kind: NodeDeclaration, Text: tok.Text, Span: Span{Start: tok.Pos, End: value.Span.End}, Children: []*Node{value}}, nil
+
+	case tok.Kind == Ident && (next.Kind == Ident && isTypeName(next.Text) ||
+		next.Kind == LBracket && next.Pos.Offset > tok.End.Offset || next.Kind == Hash):
+		// A typed declaration such as name String, optionally with a value
+		p.next()
+		typ, err := p.postfix()
+		if err != nil {
+			return nil, err
+		}
+		n := &Node{Kind: NodeDeclaration, Text: tok.Text, Span: Span{Start: tok.Pos, End: typ.Span.End}, Children: []*Node{typ}}
+		if op := p.peek(); op.Kind == Operator && op.Text == "=" {
+			p.next()
+			p.skipNewlines()
+			value, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			n.Children = append(n.Children, value)
+			n.Span.End = value.Span.End
+		}
+		return n, nil
+	}
+
+	expr, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if op := p.peek(); op.Kind == Operator && assignmentOperators[op.Text] {
+		p.next()
+		p.skipNewlines()
+		value, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		return &Node{Kind: NodeAssignment, Text: op.Text, Span: Span{Start: expr.Span.Start, End: value.Span.End}, Children: []*Node{expr, value}}, nil
+	}
+	return expr, nil
+}
+
+// expression parses an expression, which may be a conditional: cond ? then else
+func (p *parser) expression() (*Node, error) {
+	cond, err := p.binary(1)
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.Kind != Operator || tok.Text != "?" {
+		return cond, nil
+	}
+
+	p.next()
+	then, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	n := &Node{Kind: NodeConditional, Span: Span{Start: cond.Span.Start, End: then.Span.End}, Children: []*Node{cond, then}}
+	if p.peek().Kind == LBrace {
+		otherwise, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		n.Children = append(n.Children, otherwise)
+		n.Span.End = otherwise.Span.End
+	}
+	return n, nil
+}
+
+// binary parses binary operations whose operators bind at least as tightly as minPrecedence
+func (p *parser) binary(minPrecedence int) (*Node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		prec, ok := precedence[op.Text]
+		if op.Kind != Operator || !ok || prec < minPrecedence {
+			return left, nil
+		}
+		p.next()
+		p.skipNewlines()
+		right, err := p.binary(prec + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &Node{Kind: NodeBinary, Text: op.Text, Span: Span{Start: left.Span.Start, End: right.Span.End}, Children: []*Node{left, right}}
+	}
+}
+
+// unary parses an operand, possibly prefixed by unary operators
+func (p *parser) unary() (*Node, error) {
+	op := p.peek()
+	if op.Kind != Operator || !unaryOperators[op.Text] {
+		return p.postfix()
+	}
+	p.next()
+	operand, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	return &Node{Kind: NodeUnary, Text: op.Text, Span: Span{Start: op.Pos, End: operand.Span.End}, Children: []*Node{operand}}, nil
+}
+
+// postfix parses a primary expression followed by calls, indexes and member
+// accesses. An index bracket must follow without a space, since a spaced one
+// starts a list.
+func (p *parser) postfix() (*Node, error) {
+	n, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		switch {
+		case tok.Kind == LParen:
+			p.next()
+			args, end, err := p.arguments()
+			if err != nil {
+				return nil, err
+			}
+			n = &Node{Kind: NodeCall, Span: Span{Start: n.Span.Start, End: end}, Children: append([]*Node{n}, args...)}
+
+		case tok.Kind == LBracket && tok.Pos.Offset == n.Span.End.Offset:
+			p.next()
+			p.skipNewlines()
+			index, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			p.skipNewlines()
+			closing, err := p.expect(RBracket, "]")
+			if err != nil {
+				return nil, err
+			}
+			n = &Node{Kind: NodeIndex, Span: Span{Start: n.Span.Start, End: closing.End}, Children: []*Node{n, index}}
+
+		case tok.Kind == Dot:
+			p.next()
+			name, err := p.expect(Ident, "name after .")
+			if err != nil {
+				return nil, err
+			}
+			n = &Node{Kind: NodeMember, Text: name.Text, Span: Span{Start: n.Span.Start, End: name.End}, Children: []*Node{n}}
+
+		default:
+			return n, nil
+		}
+	}
+}
+
+// arguments parses call arguments after the opening parenthesis, and returns
+// them with the end of the closing one
+func (p *parser) arguments() ([]*Node, Pos, error) {
+	var args []*Node
+	for {
+		p.skipNewlines()
+		if tok := p.peek(); tok.Kind == RParen {
+			p.next()
+			return args, tok.End, nil
+		}
+
+		var arg *Node
+		if name := p.peek(); name.Kind == Ident && p.peekNext().Kind == Colon {
+			p.next()
+			p.next()
+			value, err := p.expression()
+			if err != nil {
+				return nil, Pos{}, err
+			}
+			arg = &Node{Kind: NodeArgument, Text: name.Text, Span: Span{Start: name.Pos, End: value.Span.End}, Children: []*Node{value}}
+		} else {
+			value, err := p.expression()
+			if err != nil {
+				return nil, Pos{}, err
+			}
+			arg = value
+		}
+		args = append(args, arg)
+
+		if err := p.separator(RParen, "arguments"); err != nil {
+			return nil, Pos{}, err
+		}
+	}
+}
+
+// separator moves past the comma after an element of a bracketed sequence,
+// if any, and returns a *SyntaxError unless the sequence goes on or closes
+func (p *parser) separator(closing Kind, what string) error {
+	p.skipNewlines()
+	switch tok := p.peek(); tok.Kind {
+	case Comma:
+		p.next()
+		return nil
+	case closing:
+		return nil
+	default:
+		return p.errorf(tok, "expected , or %s in %s, found %s", closingText[closing], what, describe(tok))
+	}
+}
+
+// primary parses a name, a literal, a block, a signature, a list or a
+// parenthesized expression
+func (p *parser) primary() (*Node, error) {
+	tok := p.peek()
+	switch tok.Kind {
+	case Ident:
+		p.next()
+		return &Node{Kind: NodeIdent, Text: tok.Text, Span: Span{Start: tok.Pos, End: tok.End}}, nil
+	case Number:
+		p.next()
+		return &Node{Kind: NodeNumber, Text: tok.Text, Span: Span{Start: tok.Pos, End: tok.End}}, nil
+	case String:
+		p.next()
+		return stringNode(tok)
+	case LBrace:
+		return p.block()
+	case Hash:
+		return p.signature()
+	case LBracket:
+		return p.list()
+	case LParen:
+		p.next()
+		p.skipNewlines()
+		expr, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		p.skipNewlines()
+		closing, err := p.expect(RParen, ")")
+		if err != nil {
+			return nil, err
+		}
+		return &Node{Kind: NodeGroup, Span: Span{Start: tok.Pos, End: closing.End}, Children: []*Node{expr}}, nil
+	}
+	return nil, p.errorf(tok, "unexpected %s", describe(tok))
+}
+
+// stringNode parses the expressions interpolated in a string token
+func stringNode(tok Token) (*Node, error) {
+	n := &Node{Kind: NodeString, Text: tok.Text, Span: Span{Start: tok.Pos, End: tok.End}}
+	for _, tokens := range interpolations(tok) {
+		if len(tokens) == 0 {
+			return nil, &SyntaxError{Pos: tok.Pos, Msg: "empty interpolation"}
+		}
+		end := tokens[len(tokens)-1].End
+		p := newParser(append(tokens, Token{Kind: EOF, Pos: end, End: end}))
+		expr, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		if rest := p.peek(); rest.Kind != EOF {
+			return nil, p.errorf(rest, "unexpected %s in interpolation", describe(rest))
+		}
+		n.Children = append(n.Children, expr)
+	}
+	return n, nil
+}
+
+// block parses statements between braces
+func (p *parser) block() (*Node, error) {
+	open := p.next()
+	stmts, err := p.statements(RBrace)
+	if err != nil {
+		return nil, err
+	}
+	closing := p.next()
+	return &Node{Kind: NodeBlock, Span: Span{Start: open.Pos, End: closing.End}, Children: stmts}, nil
+}
+
+// signature parses #(params) and the block that follows it on the same line, if any.
+// A parameter is a name and a type, or only a type, as for a result.
+func (p *parser) signature() (*Node, error) {
+	hash := p.next()
+	if _, err := p.expect(LParen, "( after #"); err != nil {
+		return nil, err
+	}
+
+	n := &Node{Kind: NodeSignature, Span: Span{Start: hash.Pos}}
+	for {
+		p.skipNewlines()
+		if tok := p.peek(); tok.Kind == RParen {
+			p.next()
+			n.Span.End = tok.End
+			break
+		}
+
+		param := &Node{Kind: NodeParam}
+		name, next := p.peek(), p.peekNext()
+		if name.Kind == Ident && (next.Kind == Ident || next.Kind == LBracket || next.Kind == Hash) {
+			p.next()
+			param.Text = name.Text
+		}
+		typ, err := p.postfix()
+		if err != nil {
+			return nil, err
+		}
+		param.Span = Span{Start: name.Pos, End: typ.Span.End}
+		param.Children = []*Node{typ}
+		n.Children = append(n.Children, param)
+
+		if err := p.separator(RParen, "parameters"); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.peek().Kind == LBrace {
+		body, err := p.block()
+		if err != nil {
+			return nil, err
+		}
+		n.Children = append(n.Children, body)
+		n.Span.End = body.Span.End
+	}
+	return n, nil
+}
+
+// list parses the elements of a list between brackets. An element may be a
+// key : value pair, as in a map or a map type.
+func (p *parser) list() (*Node, error) {
+	open := p.next()
+	n := &Node{Kind: NodeList, Span: Span{Start: open.Pos}}
+	for {
+		p.skipNewlines()
+		if tok := p.peek(); tok.Kind == RBracket {
+			p.next()
+			n.Span.End = tok.End
+			return n, nil
+		}
+
+		elem, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().Kind == Colon {
+			p.next()
+			p.skipNewlines()
+			value, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			elem = &Node{Kind: NodePair, Span: Span{Start: elem.Span.Start, End: value.Span.End}, Children: []*Node{elem, value}}
+		}
+		n.Children = append(n.Children, elem)
+
+		if err := p.separator(RBracket, "list"); err != nil {
+			return nil, err
+		}
+	}
+}
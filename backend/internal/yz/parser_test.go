@@ -0,0 +1,109 @@
+package yz
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// tree renders a node and its children as (kind text children...)
+func tree(n *Node) string {
+	var b strings.Builder
+	b.WriteString("(" + n.Kind.String())
+	if n.Text != "" {
+		b.WriteString(" " + n.Text)
+	}
+	for _, child := range n.Children {
+		b.WriteString(" " + tree(child))
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"precedence", "x : 1 + 2 * 3", "(program (declaration x (binary + (number 1) (binary * (number 2) (number 3)))))"},
+		{"assignment", "x = y", "(program (assignment = (ident x) (ident y)))"},
+		{"compound assignment", "x += 1", "(program (assignment += (ident x) (number 1)))"},
+		{"block with interpolation", "main : {\n println(\"hi `n`\")\n}",
+			"(program (declaration main (block (call (ident println) (string \"hi `n`\" (ident n))))))"},
+		{"named argument", "f(1, name: 2)", "(program (call (ident f) (number 1) (argument name (number 2))))"},
+		{"members", "a.b.c", "(program (member c (member b (ident a))))"},
+		{"index", "xs[0]", "(program (index (ident xs) (number 0)))"},
+		{"unary", "-x * !y", "(program (binary * (unary - (ident x)) (unary ! (ident y))))"},
+		{"group", "(1 + 2) * 3", "(program (binary * (group (binary + (number 1) (number 2))) (number 3)))"},
+		{"list", "[1, 2]", "(program (list (number 1) (number 2)))"},
+		{"dictionary", `["a": 1]`, `(program (list (pair (string "a") (number 1))))`},
+		{"list type", "names [String]", "(program (declaration names (list (ident String))))"},
+		{"signature", "add #(a Int, b Int, Int) {\n return a + b\n}",
+			"(program (declaration add (signature (param a (ident Int)) (param b (ident Int)) (param (ident Int)) " +
+				"(block (jump return (binary + (ident a) (ident b)))))))"},
+		{"conditional", "x > 0 ? { 1 } { 2 }",
+			"(program (conditional (binary > (ident x) (number 0)) (block (number 1)) (block (number 2))))"},
+		{"break", "break", "(program (jump break))"},
+		{"return", "return 1", "(program (jump return (number 1)))"},
+		{"logical operators", "a && b || c", "(program (binary || (binary && (ident a) (ident b)) (ident c)))"},
+		{"comparison", "a == b < c", "(program (binary == (ident a) (binary < (ident b) (ident c))))"},
+		{"statements on a line", "x : 1; y : 2", "(program (declaration x (number 1)) (declaration y (number 2)))"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := Parse(tt.src)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.src, err)
+			}
+			if got := tree(file.Root); got != tt.want {
+				t.Errorf("Parse(%q)\n got %s\nwant %s", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSpans(t *testing.T) {
+	file, err := Parse("x : 1 + 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	declaration := file.Root.Children[0]
+	if want := (Span{Start: Pos{Line: 1, Column: 1, Offset: 0}, End: Pos{Line: 1, Column: 10, Offset: 9}}); declaration.Span != want {
+		t.Errorf("declaration span = %+v, want %+v", declaration.Span, want)
+	}
+	value := declaration.Children[0]
+	if want := (Span{Start: Pos{Line: 1, Column: 5, Offset: 4}, End: Pos{Line: 1, Column: 10, Offset: 9}}); value.Span != want {
+		t.Errorf("value span = %+v, want %+v", value.Span, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		pos string
+		msg string
+	}{
+		{"x : ", "1:5", "unexpected end of input"},
+		{"f(1,", "1:2", "unclosed ("},
+		{"x : )", "1:5", "unexpected )"},
+		{"#(a Int", "1:2", "unclosed ("},
+		{"[1 2]", "1:4", "expected , or ] in list, found 2"},
+		{"a.", "1:3", "expected name after ., found end of input"},
+		{"x : 1 2", "1:7", "expected newline or ; after statement, found 2"},
+		{"1e+", "1:2", "expected newline or ; after statement, found e"},
+		{"{", "1:1", "unclosed {"},
+		{`"open`, "1:1", "unterminated string"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.src)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("Parse(%q) error = %v, want a *SyntaxError", tt.src, err)
+			continue
+		}
+		if syntaxErr.Pos.String() != tt.pos || syntaxErr.Msg != tt.msg {
+			t.Errorf("Parse(%q) error = %q at %s, want %q at %s", tt.src, syntaxErr.Msg, syntaxErr.Pos, tt.msg, tt.pos)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package yz
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SymbolKind is the kind of a declared name
+type SymbolKind int
+
+// Symbol kinds
+const (
+	SymbolValue SymbolKind = iota // name : value, or name Type
+	SymbolBlock                   // name : { ... } or name : #(...) { ... }
+	SymbolParam                   // parameter in a #(name Type) signature
+)
+
+// Span is a range of source code
+type Span struct {
+	Start Pos
+	End   Pos // just past the last character
+}
+
+// Contains reports whether the byte offset is within the span, including its end
+func (s Span) Contains(offset int) bool {
+	return s.Start.Offset <= offset && offset <= s.End.Offset
+}
+
+// Symbol is a name declared in Yz source
+type Symbol struct {
+	Name   string
+	Kind   SymbolKind
+	Pos    Pos    // where the name is declared
+	Scope  Span   // where the name is visible: the enclosing block, or the whole source
+	Detail string // the declaration's first line, e.g. "x : 10"
+	Global bool   // declared at the top level rather than in a block
+}
+
+// scopeFrame is an open bracket while collecting symbols
+type scopeFrame struct {
+	tok   Token
+	first int // index of the first symbol scoped to this bracket
+}
+
+// Symbols returns the names declared in tokens, in source order. Tokens that
+// don't balance are tolerated, so symbols can be found while code is edited.
+func Symbols(tokens []Token) []Symbol {
+	var symbols []Symbol
+	var stack []scopeFrame
+	end := Pos{Line: 1, Column: 1}
+	if n := len(tokens); n > 0 {
+		end = tokens[n-1].End
+	}
+
+	statementStart := true
+	paramsFirst, paramsClose := -1, -1 // parameters waiting for the block after their signature
+	for i, tok := range tokens {
+		if tok.Kind == Comment {
+			continue
+		}
+		inBlock := len(stack) == 0 || stack[len(stack)-1].tok.Kind == LBrace
+
+		if tok.Kind == Ident && statementStart && inBlock {
+			if sym, ok := declaration(tokens, i); ok {
+				sym.Scope.Start = Pos{Line: 1, Column: 1}
+				sym.Global = len(stack) == 0
+				if len(stack) > 0 {
+					sym.Scope.Start = stack[len(stack)-1].tok.Pos
+				}
+				symbols = append(symbols, sym)
+			}
+		}
+		if tok.Kind == Hash && i+1 < len(tokens) && tokens[i+1].Kind == LParen {
+			paramsFirst = len(symbols)
+			symbols, paramsClose = appendParams(symbols, tokens, i+1)
+			if paramsClose+1 >= len(tokens) || tokens[paramsClose+1].Kind != LBrace {
+				// A signature without a body only scopes its parameters to itself
+				closeScope(symbols, paramsFirst, tokens[min(paramsClose, len(tokens)-1)].End)
+				paramsFirst = -1
+			}
+		}
+
+		switch {
+		case tok.Kind.Opens():
+			first := len(symbols)
+			if tok.Kind == LBrace && i == paramsClose+1 && paramsFirst >= 0 {
+				first = paramsFirst
+				paramsFirst = -1
+			}
+			stack = append(stack, scopeFrame{tok: tok, first: first})
+		case tok.Kind.Closes() && len(stack) > 0:
+			frame := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if frame.tok.Kind == LBrace {
+				closeScope(symbols, frame.first, tok.End)
+			}
+		}
+
+		switch tok.Kind {
+		case Newline, Semicolon, Comma, LBrace:
+			statementStart = true
+		default:
+			statementStart = false
+		}
+	}
+
+	// Top-level symbols, and those in blocks left open, are visible to the end
+	for j := range symbols {
+		if symbols[j].Scope.End == (Pos{}) {
+			symbols[j].Scope.End = end
+		}
+	}
+	return symbols
+}
+
+// closeScope ends the scope of symbols[first:] that are still open at end
+func closeScope(symbols []Symbol, first int, end Pos) {
+	for j := first; j < len(symbols); j++ {
+		if symbols[j].Scope.End == (Pos{}) {
+			symbols[j].Scope.End = end
+		}
+	}
+}
+
+// declaration returns the symbol declared by the statement starting at tokens[i]
+func declaration(tokens []Token, i int) (Symbol, bool) {
+	if i+1 >= len(tokens) {
+		return Symbol{}, false
+	}
+	name, next := tokens[i], tokens[i+1]
+	sym := Symbol{Name: name.Text, Kind: SymbolValue, Pos: name.Pos, Detail: lineText(tokens, i)}
+
+	switch {
+	case next.Kind == Colon:
+		if i+2 < len(tokens) && (tokens[i+2].Kind == LBrace || tokens[i+2].Kind == Hash) {
+			sym.Kind = SymbolBlock
+		}
+	case next.Kind == Ident && isTypeName(next.Text),
+		next.Kind == LBracket && next.Pos.Offset > name.End.Offset,
+		next.Kind == Hash:
+		// A typed declaration such as name String or names [String]
+	default:
+		return Symbol{}, false
+	}
+	return sym, true
+}
+
+// appendParams adds the parameters of the signature whose opening parenthesis
+// is at tokens[open], and returns the index of its closing parenthesis
+func appendParams(symbols []Symbol, tokens []Token, open int) ([]Symbol, int) {
+	depth := 0
+	for j := open; j < len(tokens); j++ {
+		tok := tokens[j]
+		switch {
+		case tok.Kind.Opens():
+			depth++
+		case tok.Kind.Closes():
+			depth--
+			if depth == 0 {
+				return symbols, j
+			}
+		case tok.Kind == Ident && depth == 1 && j+1 < len(tokens):
+			prev, next := tokens[j-1].Kind, tokens[j+1].Kind
+			if (prev == LParen || prev == Comma) && (next == Ident || next == LBracket || next == Hash) {
+				symbols = append(symbols, Symbol{
+					Name:   tok.Text,
+					Kind:   SymbolParam,
+					Pos:    tok.Pos,
+					Scope:  Span{Start: tokens[open].Pos},
+					Detail: paramText(tokens, j),
+				})
+			}
+		}
+	}
+	return symbols, len(tokens) - 1
+}
+
+// isTypeName reports whether name is capitalized, as Yz type names are
+func isTypeName(name string) bool {
+	r, _ := utf8.DecodeRuneInString(name)
+	return unicode.IsUpper(r)
+}
+
+// lineText renders the tokens from tokens[i] to the end of its line
+func lineText(tokens []Token, i int) string {
+	end := i
+	for end < len(tokens) && tokens[end].Kind != Newline && tokens[end].Kind != EOF && tokens[end].Kind != Comment {
+		end++
+	}
+	return renderTokens(tokens[i:end])
+}
+
+// paramText renders a parameter and its type, up to the next comma or the closing parenthesis
+func paramText(tokens []Token, i int) string {
+	depth := 0
+	end := i
+	for ; end < len(tokens); end++ {
+		kind := tokens[end].Kind
+		if kind.Opens() {
+			depth++
+		} else if kind.Closes() || kind == Comma {
+			if depth == 0 {
+				break
+			}
+			if kind.Closes() {
+				depth--
+			}
+		}
+	}
+	return renderTokens(tokens[i:end])
+}
+
+// renderTokens writes tokens on one line with canonical spacing
+func renderTokens(tokens []Token) string {
+	var b strings.Builder
+	for i, tok := range tokens {
+		if i > 0 {
+			b.WriteString(space(tokens, i, false))
+		}
+		b.WriteString(tok.Text)
+	}
+	return b.String()
+}
+
+// Lookup returns the declaration of name visible at the byte offset, or nil.
+// A declaration in an inner scope hides outer ones; within one scope the last
+// declaration before offset wins, or the first one if all come after it.
+func Lookup(symbols []Symbol, name string, offset int) *Symbol {
+	var best *Symbol
+	for i := range symbols {
+		sym := &symbols[i]
+		if sym.Name != name || !sym.Scope.Contains(offset) {
+			continue
+		}
+		if best == nil || better(sym, best, offset) {
+			best = sym
+		}
+	}
+	return best
+}
+
+// better reports whether a is a closer declaration than b for a use at offset
+func better(a, b *Symbol, offset int) bool {
+	if a.Scope.Start.Offset != b.Scope.Start.Offset {
+		return a.Scope.Start.Offset > b.Scope.Start.Offset
+	}
+	aBefore, bBefore := a.Pos.Offset <= offset, b.Pos.Offset <= offset
+	if aBefore != bBefore {
+		return aBefore
+	}
+	if aBefore {
+		return a.Pos.Offset > b.Pos.Offset
+	}
+	return a.Pos.Offset < b.Pos.Offset
+}
+
+// Visible returns the declarations visible at the byte offset, one per name,
+// sorted by name
+func Visible(symbols []Symbol, offset int) []Symbol {
+	seen := make(map[string]bool)
+	var visible []Symbol
+	for _, sym := range symbols {
+		if seen[sym.Name] || !sym.Scope.Contains(offset) {
+			continue
+		}
+		seen[sym.Name] = true
+		visible = append(visible, *Lookup(symbols, sym.Name, offset))
+	}
+	sort.Slice(visible, func(i, j int) bool { return visible[i].Name < visible[j].Name })
+	return visible
+}
+
+// References returns the names in tokens that refer to sym, including those
+// interpolated in strings but not its declaration. Names after a dot are
+// members of another value and never refer to a declaration.
+func References(tokens []Token, symbols []Symbol, sym Symbol) []Token {
+	var refs []Token
+	prev := Illegal
+	for _, tok := range tokens {
+		if tok.Kind == Comment {
+			continue
+		}
+		if tok.Kind == Ident && tok.Text == sym.Name && tok.Pos != sym.Pos && prev != Dot {
+			if decl := Lookup(symbols, tok.Text, tok.Pos.Offset); decl != nil && decl.Pos == sym.Pos {
+				refs = append(refs, tok)
+			}
+		}
+		if tok.Kind == String {
+			refs = append(refs, References(Interpolated(tok), symbols, sym)...)
+		}
+		prev = tok.Kind
+	}
+	return refs
+}
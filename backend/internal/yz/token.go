@@ -0,0 +1,116 @@
+// Package yz tokenizes, parses and formats Yz source code
+package yz
+
+import "fmt"
+
+// Kind is the kind of a token
+type Kind int
+
+// Token kinds
+const (
+	Illegal Kind = iota
+	EOF
+	Newline
+	Comment  // line or block comment
+	Ident    // name, including builtins such as println
+	Number   // integer or floating point literal
+	String   // double or single quoted string, possibly interpolated, or a raw `string`
+	Operator // arithmetic, comparison, logical or assignment operator
+	LBrace
+	RBrace
+	LParen
+	RParen
+	LBracket
+	RBracket
+	Comma
+	Semicolon
+	Colon
+	Dot
+	Hash
+)
+
+// kindNames are the names of token kinds, as used in JSON
+var kindNames = [...]string{
+	Illegal:   "illegal",
+	EOF:       "eof",
+	Newline:   "newline",
+	Comment:   "comment",
+	Ident:     "ident",
+	Number:    "number",
+	String:    "string",
+	Operator:  "operator",
+	LBrace:    "lbrace",
+	RBrace:    "rbrace",
+	LParen:    "lparen",
+	RParen:    "rparen",
+	LBracket:  "lbracket",
+	RBracket:  "rbracket",
+	Comma:     "comma",
+	Semicolon: "semicolon",
+	Colon:     "colon",
+	Dot:       "dot",
+	Hash:      "hash",
+}
+
+// String returns the name of the kind
+func (k Kind) String() string {
+	if k >= 0 && int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return fmt.Sprintf("kind(%d)", int(k))
+}
+
+// Opens reports whether the kind opens a bracket pair
+func (k Kind) Opens() bool {
+	return k == LBrace || k == LParen || k == LBracket
+}
+
+// Closes reports whether the kind closes a bracket pair
+func (k Kind) Closes() bool {
+	return k == RBrace || k == RParen || k == RBracket
+}
+
+// closer returns the kind that closes an opening bracket
+func (k Kind) closer() Kind {
+	switch k {
+	case LBrace:
+		return RBrace
+	case LParen:
+		return RParen
+	case LBracket:
+		return RBracket
+	}
+	return Illegal
+}
+
+// Pos is a position in source code. Line and column start at 1, and columns
+// count characters rather than bytes.
+type Pos struct {
+	Offset int // byte offset
+	Line   int
+	Column int
+}
+
+// String returns the position as line:column
+func (p Pos) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Token is a lexical token with its source text and span
+type Token struct {
+	Kind Kind
+	Text string
+	Pos  Pos // first character
+	End  Pos // just past the last character
+}
+
+// SyntaxError reports source that can't be tokenized or whose brackets don't balance
+type SyntaxError struct {
+	Pos Pos
+	Msg string
+}
+
+// Error implements error
+func (e *SyntaxError) Error() string {
+	return e.Pos.String() + ": " + e.Msg
+}
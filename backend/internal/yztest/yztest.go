@@ -0,0 +1,294 @@
+// Package yztest runs Yz test suites. Tests are top-level blocks named test,
+// test_name or testName, such as test_add: { add(2, 3) == 5 }. A test passes
+// unless it evaluates to false or the program stops while it runs. The suite
+// is compiled into one program with the code under test and a generated main
+// that calls each test in turn, printing markers the results are read from.
+package yztest
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"yz-playground/internal/yz"
+)
+
+// TestsFile is the name the tests go by in compiler messages
+const TestsFile = "tests.yz"
+
+// marker starts the lines the generated main prints around each test
+const marker = "__YZ_TEST__"
+
+// programMain is what the code's own main is renamed to, since the generated
+// main takes its place
+const programMain = "_yz_program_main"
+
+// Test statuses
+const (
+	StatusPassed  = "passed"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+var (
+	// ErrNoTests is returned for tests without any test block
+	ErrNoTests = errors.New("no tests found; tests are top-level blocks named test, test_name or testName")
+	// ErrTestsMain is returned for tests that declare main, which the runner provides
+	ErrTestsMain = errors.New("tests can't declare main; the test runner provides it")
+)
+
+// Suite is a test program ready to run
+type Suite struct {
+	Source string   // the code, the tests and the generated main, as one main.yz
+	Tests  []string // names of the tests, in the order they run
+
+	testsLine  int // line of Source where the tests start
+	runnerLine int // line of Source where the generated main starts
+}
+
+// Build combines code and the tests into one program. Tests that can't be
+// parsed return a *yz.SyntaxError with positions in the tests. Code that
+// can't be parsed is left for the compiler to report.
+func Build(code, tests string) (*Suite, error) {
+	parsed, err := yz.Parse(tests)
+	if err != nil {
+		return nil, err
+	}
+	s := &Suite{}
+	for _, n := range parsed.Root.Children {
+		if n.Kind != yz.NodeDeclaration {
+			continue
+		}
+		if n.Text == "main" {
+			return nil, ErrTestsMain
+		}
+		if isTestName(n.Text) && isTestBody(n.Children[len(n.Children)-1]) {
+			s.Tests = append(s.Tests, n.Text)
+		}
+	}
+	if len(s.Tests) == 0 {
+		return nil, ErrNoTests
+	}
+
+	var src strings.Builder
+	writeLines(&src, renameMain(code))
+	s.testsLine = strings.Count(src.String(), "\n") + 1
+	writeLines(&src, tests)
+	s.runnerLine = strings.Count(src.String(), "\n") + 1
+	src.WriteString("main: {\n")
+	for _, name := range s.Tests {
+		fmt.Fprintf(&src, "    println(%q)\n", marker+" run "+name)
+		fmt.Fprintf(&src, "    println(%q, %s())\n", marker+" result ", name)
+	}
+	src.WriteString("}\n")
+	s.Source = src.String()
+	return s, nil
+}
+
+// isTestName reports whether a top-level name declares a test: test, or test
+// followed by an underscore or a capital letter
+func isTestName(name string) bool {
+	rest, ok := strings.CutPrefix(name, "test")
+	return ok && (rest == "" || rest[0] == '_' || rest[0] >= 'A' && rest[0] <= 'Z')
+}
+
+// isTestBody reports whether a declared value can run as a test: a block, or
+// a signature with a body and no parameters
+func isTestBody(n *yz.Node) bool {
+	switch n.Kind {
+	case yz.NodeBlock:
+		return true
+	case yz.NodeSignature:
+		for _, child := range n.Children {
+			if child.Kind == yz.NodeParam && child.Text != "" {
+				return false
+			}
+		}
+		return len(n.Children) > 0 && n.Children[len(n.Children)-1].Kind == yz.NodeBlock
+	}
+	return false
+}
+
+// writeLines writes src to b, ending it with a newline
+func writeLines(b *strings.Builder, src string) {
+	b.WriteString(src)
+	if !strings.HasSuffix(src, "\n") {
+		b.WriteString("\n")
+	}
+}
+
+// renameMain renames a main declared at the top level of code, and its uses,
+// so the generated main can take its place
+func renameMain(code string) string {
+	parsed, err := yz.Parse(code)
+	if err != nil {
+		return code
+	}
+	symbols := yz.Symbols(parsed.Tokens)
+	for _, sym := range symbols {
+		if !sym.Global || sym.Name != "main" {
+			continue
+		}
+		offsets := []int{sym.Pos.Offset}
+		for _, ref := range yz.References(parsed.Tokens, symbols, sym) {
+			offsets = append(offsets, ref.Pos.Offset)
+		}
+		// Replace from the end so earlier offsets stay valid
+		sort.Sort(sort.Reverse(sort.IntSlice(offsets)))
+		for _, offset := range offsets {
+			code = code[:offset] + programMain + code[offset+len(sym.Name):]
+		}
+		break
+	}
+	return code
+}
+
+// compilerPosition matches positions in main.yz in compiler messages
+var compilerPosition = regexp.MustCompile(`main\.yz:(\d+)(:\d+)?`)
+
+// MapPositions rewrites the main.yz positions in compiler output that fall in
+// the tests to positions in TestsFile
+func (s *Suite) MapPositions(output string) string {
+	return compilerPosition.ReplaceAllStringFunc(output, func(match string) string {
+		groups := compilerPosition.FindStringSubmatch(match)
+		line, err := strconv.Atoi(groups[1])
+		if err != nil || line < s.testsLine || line >= s.runnerLine {
+			return match
+		}
+		return fmt.Sprintf("%s:%d%s", TestsFile, line-s.testsLine+1, groups[2])
+	})
+}
+
+// Recorder collects the output of a test program, noting when each line
+// arrives. It is meant as the program's output stream.
+type Recorder struct {
+	mutex   sync.Mutex
+	lines   []line
+	partial string
+}
+
+// line is a line of program output
+type line struct {
+	text string
+	at   time.Time
+}
+
+// NewRecorder creates an empty recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Write implements io.Writer
+func (r *Recorder) Write(p []byte) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	text := r.partial + string(p)
+	for {
+		i := strings.IndexByte(text, '\n')
+		if i < 0 {
+			break
+		}
+		r.lines = append(r.lines, line{text[:i], now})
+		text = text[i+1:]
+	}
+	r.partial = text
+	return len(p), nil
+}
+
+// finish returns every line, including a last one without a newline
+func (r *Recorder) finish() []line {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	lines := r.lines
+	if r.partial != "" {
+		lines = append(lines, line{r.partial, time.Now()})
+	}
+	return lines
+}
+
+// Result is the outcome of one test
+type Result struct {
+	Name     string
+	Status   string
+	Duration time.Duration
+	Message  string // why the test failed or was skipped
+	Output   string // what the test printed
+}
+
+// Results reads the results of the tests from the output recorded while the
+// suite ran. stopped describes why the program ended early, e.g. "the
+// program crashed", or is empty if it ran to the end. Tests the program
+// never reached are skipped. It also returns the output printed outside
+// any test.
+func (s *Suite) Results(r *Recorder, stopped string) ([]Result, string) {
+	lines := r.finish()
+	results := make([]Result, 0, len(s.Tests))
+	current := -1 // index of the running test in results
+	var output strings.Builder
+	var started, lastAt time.Time
+
+	for _, l := range lines {
+		lastAt = l.at
+
+		// Markers are only taken in the order the generated main prints them,
+		// so one printed by the program itself is just output
+		text, event, _ := strings.Cut(l.text, marker+" ")
+		kind, value, _ := strings.Cut(event, " ")
+		run := kind == "run" && current < 0 && len(results) < len(s.Tests) && value == s.Tests[len(results)]
+		result := kind == "result" && current >= 0
+		if !run && !result {
+			text = l.text
+		}
+		if text != "" || (!run && !result) {
+			if current >= 0 {
+				results[current].Output += text + "\n"
+			} else {
+				output.WriteString(text + "\n")
+			}
+		}
+
+		switch {
+		case run:
+			results = append(results, Result{Name: value, Status: StatusPassed})
+			current = len(results) - 1
+			started = l.at
+		case result:
+			results[current].Duration = l.at.Sub(started)
+			if strings.TrimSpace(value) == "false" {
+				results[current].Status = StatusFailed
+				results[current].Message = results[current].Name + " evaluated to false"
+			}
+			current = -1
+		}
+	}
+
+	if stopped == "" {
+		stopped = "the program ended early"
+	}
+	message := "not run: " + stopped
+	if current >= 0 {
+		// The program stopped during this test
+		if !lastAt.After(started) {
+			lastAt = time.Now()
+		}
+		results[current].Duration = lastAt.Sub(started)
+		results[current].Status = StatusFailed
+		results[current].Message = stopped
+		message = "not run: the program stopped during " + results[current].Name
+	}
+	for _, name := range s.Tests[len(results):] {
+		results = append(results, Result{Name: name, Status: StatusSkipped, Message: message})
+	}
+	for i := range results {
+		results[i].Output = strings.TrimSuffix(results[i].Output, "\n")
+	}
+	return results, strings.TrimSuffix(output.String(), "\n")
+}
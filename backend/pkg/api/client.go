@@ -0,0 +1,271 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIKeyHeader carries the API key on requests
+const APIKeyHeader = "X-API-Key"
+
+// BasePath prefixes the paths of the current API version
+const BasePath = "/api/v1"
+
+// Retry defaults used when ClientOptions leaves them unset
+const (
+	defaultMaxRetries   = 3
+	defaultRetryBackoff = 500 * time.Millisecond
+	maxRetryBackoff     = 30 * time.Second
+)
+
+// ClientOptions configures a Client
+type ClientOptions struct {
+	APIKey     string
+	HTTPClient *http.Client // defaults to http.DefaultClient
+
+	// MaxRetries bounds how often a request is retried after an error that
+	// Error.Temporary reports as temporary. Zero means the default of 3; a
+	// negative value disables retries.
+	MaxRetries int
+	// RetryBackoff is the wait before the first retry, doubled for each one
+	// after, when the server doesn't say how long to wait with Retry-After
+	RetryBackoff time.Duration
+}
+
+// Client calls the playground API
+type Client struct {
+	baseURL      string
+	apiKey       string
+	httpClient   *http.Client
+	maxRetries   int
+	retryBackoff time.Duration
+}
+
+// NewClient creates a client for the backend at baseURL, e.g. http://localhost:8080
+func NewClient(baseURL string, opts ClientOptions) *Client {
+	c := &Client{
+		baseURL:      strings.TrimRight(baseURL, "/") + BasePath,
+		apiKey:       opts.APIKey,
+		httpClient:   opts.HTTPClient,
+		maxRetries:   opts.MaxRetries,
+		retryBackoff: opts.RetryBackoff,
+	}
+	if c.httpClient == nil {
+		c.httpClient = http.DefaultClient
+	}
+	if c.maxRetries == 0 {
+		c.maxRetries = defaultMaxRetries
+	} else if c.maxRetries < 0 {
+		c.maxRetries = 0
+	}
+	if c.retryBackoff <= 0 {
+		c.retryBackoff = defaultRetryBackoff
+	}
+	return c
+}
+
+// Execute compiles and runs code and waits for the result
+func (c *Client) Execute(ctx context.Context, req ExecuteRequest) (*ExecuteResponse, error) {
+	var result ExecuteResponse
+	if err := c.call(ctx, http.MethodPost, "/execute", req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ExecuteStream compiles and runs code, passing program output to onOutput as
+// it is printed, and returns the final result
+func (c *Client) ExecuteStream(ctx context.Context, req ExecuteRequest, onOutput func(string)) (*ExecuteResponse, error) {
+	resp, err := c.do(ctx, http.MethodPost, "/execute/stream", req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result *ExecuteResponse
+	err = readEvents(resp.Body, func(name string, data []byte) error {
+		switch name {
+		case EventOutput:
+			var event OutputEvent
+			if err := json.Unmarshal(data, &event); err != nil {
+				return fmt.Errorf("failed to decode output event: %w", err)
+			}
+			onOutput(event.Output)
+		case EventResult:
+			result = &ExecuteResponse{}
+			if err := json.Unmarshal(data, result); err != nil {
+				return fmt.Errorf("failed to decode result event: %w", err)
+			}
+		case EventError:
+			var event ErrorResponse
+			if err := json.Unmarshal(data, &event); err != nil {
+				return fmt.Errorf("failed to decode error event: %w", err)
+			}
+			return newErrorFromBody(0, event.Error)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, fmt.Errorf("event stream ended without a result")
+	}
+	return result, nil
+}
+
+// Compile builds code without running it
+func (c *Client) Compile(ctx context.Context, req CompileRequest) (*CompileResponse, error) {
+	var result CompileResponse
+	if err := c.call(ctx, http.MethodPost, "/compile", req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Config returns the limits that apply to the client's API key
+func (c *Client) Config(ctx context.Context) (*ConfigResponse, error) {
+	var result ConfigResponse
+	if err := c.call(ctx, http.MethodGet, "/config", nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CompilerVersion returns the version of the sandbox's Yz compiler
+func (c *Client) CompilerVersion(ctx context.Context) (string, error) {
+	var result VersionResponse
+	if err := c.call(ctx, http.MethodGet, "/compiler/version", nil, &result); err != nil {
+		return "", err
+	}
+	return result.Version, nil
+}
+
+// call sends a request and decodes the JSON response into result
+func (c *Client) call(ctx context.Context, method, path string, body, result any) error {
+	resp, err := c.do(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// do sends a request, retrying after temporary errors, and returns a
+// successful response or an *Error. Each retry waits as long as the server's
+// Retry-After asks, or with exponential backoff when it doesn't say.
+func (c *Client) do(ctx context.Context, method, path string, body any) (*http.Response, error) {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+	}
+
+	backoff := c.retryBackoff
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if c.apiKey != "" {
+			req.Header.Set(APIKeyHeader, c.apiKey)
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode < 400 {
+			return resp, nil
+		}
+
+		apiErr := newError(resp)
+		resp.Body.Close()
+		if !apiErr.Temporary() || attempt >= c.maxRetries {
+			return nil, apiErr
+		}
+
+		wait := apiErr.RetryAfter
+		if wait <= 0 {
+			wait = backoff
+			backoff = min(backoff*2, maxRetryBackoff)
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// newError builds an *Error from a failed response
+func newError(resp *http.Response) *Error {
+	var body ErrorResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil || body.Error.Message == "" {
+		body.Error.Message = http.StatusText(resp.StatusCode)
+	}
+	apiErr := newErrorFromBody(resp.StatusCode, body.Error)
+
+	if value := resp.Header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil {
+			apiErr.RetryAfter = time.Duration(seconds) * time.Second
+		} else if at, err := http.ParseTime(value); err == nil {
+			apiErr.RetryAfter = time.Until(at)
+		}
+	}
+	return apiErr
+}
+
+// readEvents reads server-sent events from r, calling handle with each event's
+// name and data until the stream ends or handle returns an error
+func readEvents(r io.Reader, handle func(name string, data []byte) error) error {
+	reader := bufio.NewReader(r)
+	var name string
+	var data [][]byte
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("failed to read event stream: %w", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" {
+			// A blank line ends the event
+			if len(data) > 0 {
+				if err := handle(name, bytes.Join(data, []byte("\n"))); err != nil {
+					return err
+				}
+			}
+			name, data = "", nil
+		} else if field, value, ok := strings.Cut(line, ":"); ok {
+			value = strings.TrimPrefix(value, " ")
+			switch field {
+			case "event":
+				name = value
+			case "data":
+				data = append(data, []byte(value))
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Errors that an *Error matches with errors.Is, by status code
+var (
+	ErrBadRequest   = errors.New("bad request")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrUnavailable  = errors.New("service unavailable")
+)
+
+// Error is returned by Client for a request the server rejected
+type Error struct {
+	StatusCode int // 0 when the error arrived in an event stream
+	Code       ErrorCode
+	Message    string
+	Details    map[string]any
+	RequestID  string
+	RetryAfter time.Duration // how long the server asked clients to wait, if it did
+}
+
+// newErrorFromBody creates an *Error from an error envelope
+func newErrorFromBody(statusCode int, body ErrorBody) *Error {
+	return &Error{
+		StatusCode: statusCode,
+		Code:       body.Code,
+		Message:    body.Message,
+		Details:    body.Details,
+		RequestID:  body.RequestID,
+	}
+}
+
+// Error implements error
+func (e *Error) Error() string {
+	msg := e.Message
+	if e.Code != "" {
+		msg = string(e.Code) + ": " + msg
+	}
+	if e.StatusCode == 0 {
+		return msg
+	}
+	return fmt.Sprintf("server returned %d: %s", e.StatusCode, msg)
+}
+
+// Is lets errors.Is match an *Error against the sentinel for its status code
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrUnavailable:
+		return e.StatusCode == http.StatusServiceUnavailable
+	}
+	return false
+}
+
+// Temporary reports whether the request may succeed if retried later: the
+// caller is rate limited, the execution queue is full, or the server is
+// unavailable and said when to try again
+func (e *Error) Temporary() bool {
+	switch {
+	case e.StatusCode == http.StatusTooManyRequests, e.Code == CodeQueueFull:
+		return true
+	case e.StatusCode == http.StatusServiceUnavailable:
+		return e.RetryAfter > 0
+	}
+	return false
+}
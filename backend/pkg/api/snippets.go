@@ -0,0 +1,49 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// SnippetParam is the query parameter the playground frontend loads shared code from
+const SnippetParam = "code"
+
+// ShareURL builds a link to the playground frontend at frontendURL that opens
+// code in the editor, the same kind of link the editor's Share button copies
+func ShareURL(frontendURL, code string) (string, error) {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return "", errors.New("nothing to share, the code is empty")
+	}
+	u, err := url.Parse(frontendURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid frontend URL: %w", err)
+	}
+
+	// Match the browser's encodeURIComponent, which escapes spaces as %20 rather than +
+	query := u.Query()
+	query.Set(SnippetParam, code)
+	u.RawQuery = strings.ReplaceAll(query.Encode(), "+", "%20")
+	return u.String(), nil
+}
+
+// SharedCode extracts the code from a playground link
+func SharedCode(link string) (string, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", fmt.Errorf("invalid link: %w", err)
+	}
+	code := u.Query().Get(SnippetParam)
+	if code == "" {
+		return "", fmt.Errorf("link has no %q parameter", SnippetParam)
+	}
+
+	// The editor decodes the parameter once more after reading it; do the
+	// same so the code matches what the link opens
+	if decoded, err := url.PathUnescape(code); err == nil {
+		code = decoded
+	}
+	return code, nil
+}
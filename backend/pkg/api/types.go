@@ -6,16 +6,406 @@ type ExecuteRequest struct {
 	Timeout           int    `json:"timeout,omitempty"`
 	Memory            int    `json:"memory,omitempty"`
 	ShowGeneratedCode bool   `json:"show_generated_code,omitempty"`
+	// Files are further .yz project files by slash-separated relative path; Code
+	// becomes main.yz and the project is built with yzc build
+	Files map[string]string `json:"files,omitempty"`
+	// Profile runs the program with CPU and heap profiling
+	Profile bool `json:"profile,omitempty"`
+	// Compiler names one of ConfigResponse.Compilers to build with instead
+	// of the default compiler
+	Compiler string `json:"compiler,omitempty"`
 }
 
 // ExecuteResponse represents a code execution response
 type ExecuteResponse struct {
-	Success       bool   `json:"success"`
-	Output        string `json:"output"`
-	GeneratedCode string `json:"generated_code,omitempty"`
-	Error         string `json:"error"`
-	ExecutionTime int    `json:"execution_time"`
-	MemoryUsed    int    `json:"memory_used"`
+	Success       bool      `json:"success"`
+	Output        string    `json:"output"`
+	GeneratedCode string    `json:"generated_code,omitempty"`
+	Error         string    `json:"error"`
+	ErrorCode     ErrorCode `json:"error_code,omitempty"` // set when success is false
+	Truncated     bool      `json:"truncated"`            // output passed the size limit and the run was stopped
+	ExecutionTime int       `json:"execution_time"`
+	MemoryUsed    int       `json:"memory_used"`
+	Profile       *Profile  `json:"profile,omitempty"` // set when profiling was asked for and the program ran
+}
+
+// Profile holds the profiles of a profiled run. A table or raw profile is
+// missing if the program didn't write it, as when it crashes or is stopped
+// before its main returns.
+type Profile struct {
+	CPU       *ProfileTable `json:"cpu,omitempty"`        // CPU time of the busiest functions
+	Heap      *ProfileTable `json:"heap,omitempty"`       // bytes allocated by the functions allocating most
+	CPUPprof  []byte        `json:"cpu_pprof,omitempty"`  // the CPU profile in gzipped pprof format, base64 in JSON
+	HeapPprof []byte        `json:"heap_pprof,omitempty"` // the heap profile in gzipped pprof format, base64 in JSON
+	Error     string        `json:"error,omitempty"`      // why the profiles are missing or couldn't be read
+}
+
+// ProfileTable lists the functions a profile's samples were spent in
+type ProfileTable struct {
+	SampleType string            `json:"sample_type"` // cpu or alloc_space
+	Unit       string            `json:"unit"`        // nanoseconds or bytes
+	Total      int64             `json:"total"`
+	Functions  []ProfileFunction `json:"functions"` // by decreasing flat, then cum
+}
+
+// ProfileFunction is a row of a profile table. Functions of the generated
+// program are named after the Yz declarations they come from where possible.
+type ProfileFunction struct {
+	Name        string  `json:"name"` // the Yz name if yz is set, otherwise the Go name
+	GoName      string  `json:"go_name"`
+	Yz          bool    `json:"yz"`
+	File        string  `json:"file,omitempty"`
+	Line        int     `json:"line,omitempty"`
+	Flat        int64   `json:"flat"` // spent in the function itself
+	FlatPercent float64 `json:"flat_percent"`
+	Cum         int64   `json:"cum"` // spent in the function and what it calls
+	CumPercent  float64 `json:"cum_percent"`
+}
+
+// CompileRequest represents a request to compile code without running it
+type CompileRequest struct {
+	Code    string `json:"code" binding:"required"`
+	Timeout int    `json:"timeout,omitempty"`
+	Memory  int    `json:"memory,omitempty"`
+	// Files are further project files, as in ExecuteRequest
+	Files map[string]string `json:"files,omitempty"`
+	// Compiler names the compiler version to build with, as in ExecuteRequest
+	Compiler string `json:"compiler,omitempty"`
+}
+
+// CompileResponse represents a compilation response
+type CompileResponse struct {
+	Success     bool      `json:"success"`
+	Output      string    `json:"output"`
+	Error       string    `json:"error"`
+	ErrorCode   ErrorCode `json:"error_code,omitempty"` // set when success is false
+	Truncated   bool      `json:"truncated"`            // output passed the size limit and the build was stopped
+	CompileTime int       `json:"compile_time"`
+}
+
+// FormatRequest represents a request to format code in canonical Yz style
+type FormatRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// FormatResponse represents a formatting response. Code that can't be parsed
+// isn't formatted, and the response says where parsing failed.
+type FormatResponse struct {
+	Success   bool      `json:"success"`
+	Code      string    `json:"code"`    // the formatted code, or the request's code if it couldn't be parsed
+	Changed   bool      `json:"changed"` // whether formatting changed the code
+	Error     string    `json:"error"`
+	ErrorCode ErrorCode `json:"error_code,omitempty"` // set when success is false
+	Position  *Position `json:"position,omitempty"`   // where the error is
+}
+
+// BenchmarkRequest represents a request to build code once and time several
+// runs of it
+type BenchmarkRequest struct {
+	Code    string            `json:"code" binding:"required"`
+	Files   map[string]string `json:"files,omitempty"`   // further project files, as in ExecuteRequest
+	Runs    int               `json:"runs,omitempty"`    // default 10, at most max_benchmark_runs
+	Timeout int               `json:"timeout,omitempty"` // for the build and every run together
+	Memory  int               `json:"memory,omitempty"`
+}
+
+// BenchmarkResponse represents the statistics of a benchmark. The statistics
+// cover the runs that completed; a failed run ends the benchmark.
+type BenchmarkResponse struct {
+	Success     bool           `json:"success"`
+	Output      string         `json:"output"` // the first run's output
+	Error       string         `json:"error"`
+	ErrorCode   ErrorCode      `json:"error_code,omitempty"` // set when success is false
+	Runs        int            `json:"runs"`                 // runs completed
+	CompileTime int            `json:"compile_time"`
+	WallTime    BenchmarkStats `json:"wall_time"`   // in milliseconds
+	CPUTime     BenchmarkStats `json:"cpu_time"`    // user and system time in milliseconds
+	PeakMemory  BenchmarkStats `json:"peak_memory"` // resident memory in bytes
+}
+
+// BenchmarkStats summarizes one measurement across the runs of a benchmark.
+// CPU time and memory are 0 when the sandbox can't measure them.
+type BenchmarkStats struct {
+	Min    float64 `json:"min"`
+	Median float64 `json:"median"`
+	P95    float64 `json:"p95"`
+}
+
+// TestRequest represents a request to run Yz tests against code. Tests holds
+// Yz source whose top-level blocks named test, test_name or testName are the
+// tests; each passes unless it evaluates to false or the program stops
+// while it runs.
+type TestRequest struct {
+	Code    string            `json:"code" binding:"required"`
+	Tests   string            `json:"tests" binding:"required"`
+	Files   map[string]string `json:"files,omitempty"` // further project files, as in ExecuteRequest
+	Timeout int               `json:"timeout,omitempty"`
+	Memory  int               `json:"memory,omitempty"`
+}
+
+// TestResponse represents the results of a test run
+type TestResponse struct {
+	Success       bool         `json:"success"` // every test passed
+	Tests         []TestResult `json:"tests"`
+	Passed        int          `json:"passed"`
+	Failed        int          `json:"failed"`
+	Skipped       int          `json:"skipped"`
+	Output        string       `json:"output"` // printed outside any test
+	Error         string       `json:"error"`
+	ErrorCode     ErrorCode    `json:"error_code,omitempty"` // set when the tests couldn't all run
+	Position      *Position    `json:"position,omitempty"`   // where a syntax error in the tests is
+	CompileTime   int          `json:"compile_time"`
+	ExecutionTime int          `json:"execution_time"`
+}
+
+// Test statuses
+const (
+	TestPassed  = "passed"
+	TestFailed  = "failed"
+	TestSkipped = "skipped" // not run because an earlier test stopped the program, or it didn't compile
+)
+
+// TestResult is the outcome of one test
+type TestResult struct {
+	Name       string  `json:"name"`
+	Status     string  `json:"status"`
+	DurationMs float64 `json:"duration_ms"`
+	Message    string  `json:"message,omitempty"` // why the test failed or was skipped
+	Output     string  `json:"output,omitempty"`  // what the test printed
+}
+
+// Verdicts of the cases of an exercise submission
+const (
+	VerdictAccepted     = "AC"  // the output matched
+	VerdictWrongAnswer  = "WA"  // the output didn't match, or passed the output limit
+	VerdictTimeLimit    = "TLE" // the case ran past the time limit
+	VerdictMemoryLimit  = "MLE" // the case was killed for running out of memory
+	VerdictRuntimeError = "RE"  // the program exited with an error
+	VerdictCompileError = "CE"  // the program didn't compile
+)
+
+// ExerciseListResponse lists the exercises
+type ExerciseListResponse struct {
+	Exercises []ExerciseSummary `json:"exercises"`
+}
+
+// ExerciseSummary identifies an exercise in a list
+type ExerciseSummary struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// ExerciseResponse represents an exercise. Its cases are hidden, apart from
+// those marked as samples.
+type ExerciseResponse struct {
+	ID          string           `json:"id"`
+	Title       string           `json:"title"`
+	Prompt      string           `json:"prompt"` // Markdown
+	StarterCode string           `json:"starter_code"`
+	TimeLimit   int              `json:"time_limit"`   // in milliseconds per case
+	MemoryLimit int              `json:"memory_limit"` // in MB, 0 for the server's limit
+	Cases       int              `json:"cases"`        // how many cases a submission runs on
+	Samples     []ExerciseSample `json:"samples"`
+}
+
+// ExerciseSample is a case shown with its exercise
+type ExerciseSample struct {
+	Stdin    string `json:"stdin"`
+	Expected string `json:"expected"`
+}
+
+// SubmitRequest represents a solution to an exercise
+type SubmitRequest struct {
+	Code  string            `json:"code" binding:"required"`
+	Files map[string]string `json:"files,omitempty"` // further project files, as in ExecuteRequest
+}
+
+// SubmitResponse represents the verdicts on a submission. It never carries
+// the input, expected output or program output of hidden cases.
+type SubmitResponse struct {
+	Success     bool          `json:"success"` // every case was accepted
+	Verdict     string        `json:"verdict"` // AC, or the verdict of the first case that wasn't accepted
+	Cases       []CaseVerdict `json:"cases"`
+	Passed      int           `json:"passed"`
+	Total       int           `json:"total"`
+	Error       string        `json:"error"`                // the compiler's output when the program didn't compile
+	ErrorCode   ErrorCode     `json:"error_code,omitempty"` // set when the cases couldn't all run
+	CompileTime int           `json:"compile_time"`
+}
+
+// CaseVerdict is the verdict on one case of a submission
+type CaseVerdict struct {
+	Case       int     `json:"case"` // numbered from 1
+	Verdict    string  `json:"verdict"`
+	TimeMs     float64 `json:"time_ms"`
+	MemoryUsed int64   `json:"memory_used"` // peak memory in bytes, 0 if unknown
+	Message    string  `json:"message,omitempty"`
+	Sample     bool    `json:"sample"`
+	Output     string  `json:"output,omitempty"` // what the program printed, for samples only
+}
+
+// LintRequest represents a request to check code for suspicious constructs
+type LintRequest struct {
+	Code  string          `json:"code" binding:"required"`
+	Rules map[string]bool `json:"rules,omitempty"` // rules to enable or disable; rules not listed are enabled
+}
+
+// LintResponse represents a lint response. Code that can't be parsed isn't
+// linted, and the response says where parsing failed.
+type LintResponse struct {
+	Success   bool          `json:"success"`
+	Warnings  []LintWarning `json:"warnings"`
+	Error     string        `json:"error"`
+	ErrorCode ErrorCode     `json:"error_code,omitempty"` // set when success is false
+	Position  *Position     `json:"position,omitempty"`   // where the error is
+}
+
+// LintWarning is a problem found by a lint rule
+type LintWarning struct {
+	Rule     string   `json:"rule"`
+	Message  string   `json:"message"`
+	Position Position `json:"position"`
+	End      Position `json:"end"`           // just past the code the warning is about
+	Fix      *LintFix `json:"fix,omitempty"` // set when the problem can be fixed automatically
+}
+
+// LintFix is a set of edits that resolves a warning. The edits don't overlap
+// and are listed in source order.
+type LintFix struct {
+	Description string     `json:"description"`
+	Edits       []TextEdit `json:"edits"`
+}
+
+// TextEdit replaces the code between two positions with new text
+type TextEdit struct {
+	Position Position `json:"position"`
+	End      Position `json:"end"`
+	NewText  string   `json:"new_text"`
+}
+
+// ParseRequest represents a request to tokenize and parse code
+type ParseRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// ParseResponse represents a parse response. Code that can't be parsed has no
+// tree, but still has the tokens read before the error.
+type ParseResponse struct {
+	Success   bool          `json:"success"`
+	Tokens    []SyntaxToken `json:"tokens"`
+	Tree      *SyntaxNode   `json:"tree,omitempty"`
+	Error     string        `json:"error"`
+	ErrorCode ErrorCode     `json:"error_code,omitempty"` // set when success is false
+	Position  *Position     `json:"position,omitempty"`   // where the error is
+}
+
+// SyntaxToken is a token of Yz code, including comments and newlines
+type SyntaxToken struct {
+	Kind     string   `json:"kind"` // e.g. ident, number, string, operator, comment, newline, lbrace
+	Text     string   `json:"text"`
+	Position Position `json:"position"`
+	End      Position `json:"end"`
+}
+
+// SyntaxNode is a node of a Yz syntax tree
+type SyntaxNode struct {
+	Kind     string        `json:"kind"`           // e.g. program, block, declaration, call, ident
+	Text     string        `json:"text,omitempty"` // name, operator or literal, depending on the kind
+	Position Position      `json:"position"`
+	End      Position      `json:"end"`
+	Children []*SyntaxNode `json:"children,omitempty"`
+}
+
+// ImportResponse represents a project unpacked from an archive. Code is the
+// archive's main.yz, and Files are its other .yz files, ready to be sent as
+// an ExecuteRequest.
+type ImportResponse struct {
+	Code    string            `json:"code"`
+	Files   map[string]string `json:"files"`
+	Skipped []string          `json:"skipped"` // paths of archived files that aren't Yz sources
+}
+
+// ExportRequest represents a request to pack a project into an archive
+type ExportRequest struct {
+	Code          string            `json:"code" binding:"required"`
+	Files         map[string]string `json:"files,omitempty"`
+	GeneratedCode string            `json:"generated_code,omitempty"` // added as .yzplay/main.go
+	Output        string            `json:"output,omitempty"`         // output of the last run, added as .yzplay/output.txt
+	Format        string            `json:"format,omitempty"`         // zip (the default) or tar.gz
+}
+
+// Position is a location in source code. Line and column start at 1, and
+// columns count characters rather than bytes.
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	Offset int `json:"offset"` // byte offset from the start of the code
+}
+
+// Event names sent by the streaming execute endpoint as server-sent events
+const (
+	EventOutput = "output" // data is an OutputEvent
+	EventResult = "result" // data is an ExecuteResponse, sent once at the end
+	EventError  = "error"  // data is an ErrorResponse, sent instead of a result
+)
+
+// OutputEvent carries program output from a streamed execution
+type OutputEvent struct {
+	Output string `json:"output"`
+}
+
+// ErrorCode identifies the kind of error in an ErrorResponse or a failed execution
+type ErrorCode string
+
+// Error codes returned by the API, either in an ErrorResponse when a request
+// is rejected or as the error_code of an execution that failed
+const (
+	CodeInvalidRequest     ErrorCode = "INVALID_REQUEST"
+	CodeTooLarge           ErrorCode = "CODE_TOO_LARGE"
+	CodeUnauthorized       ErrorCode = "UNAUTHORIZED"
+	CodeRateLimited        ErrorCode = "RATE_LIMITED"
+	CodeNotFound           ErrorCode = "NOT_FOUND"
+	CodeQueueFull          ErrorCode = "QUEUE_FULL"
+	CodeSandboxUnavailable ErrorCode = "SANDBOX_UNAVAILABLE"
+	CodeInternal           ErrorCode = "INTERNAL_ERROR"
+
+	CodeSyntaxError  ErrorCode = "SYNTAX_ERROR"
+	CodeCompileError ErrorCode = "COMPILE_ERROR"
+	CodeRuntimeError ErrorCode = "RUNTIME_ERROR"
+	CodeTimeout      ErrorCode = "TIMEOUT"
+	CodeMemoryLimit  ErrorCode = "MEMORY_LIMIT"
+	CodeOutputLimit  ErrorCode = "OUTPUT_LIMIT"
+)
+
+// ErrorCodes lists every error code, for documentation and validation
+var ErrorCodes = []ErrorCode{
+	CodeInvalidRequest,
+	CodeTooLarge,
+	CodeUnauthorized,
+	CodeRateLimited,
+	CodeNotFound,
+	CodeQueueFull,
+	CodeSandboxUnavailable,
+	CodeInternal,
+	CodeSyntaxError,
+	CodeCompileError,
+	CodeRuntimeError,
+	CodeTimeout,
+	CodeMemoryLimit,
+	CodeOutputLimit,
+}
+
+// ErrorResponse is the body of every API error response
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// ErrorBody describes an API error
+type ErrorBody struct {
+	Code      ErrorCode      `json:"code"`
+	Message   string         `json:"message"`
+	Details   map[string]any `json:"details,omitempty"`
+	RequestID string         `json:"request_id,omitempty"`
 }
 
 // ConfigResponse represents the API configuration response
@@ -23,6 +413,18 @@ type ConfigResponse struct {
 	MaxExecutionTime int `json:"max_execution_time"`
 	MaxMemory        int `json:"max_memory"`
 	MaxCodeSize      int `json:"max_code_size"`
+	MaxOutputSize    int `json:"max_output_size"`
+	MaxProjectFiles  int `json:"max_project_files"`
+	MaxPathDepth     int `json:"max_path_depth"`
+	MaxArchiveSize   int `json:"max_archive_size"`
+	MaxBenchmarkRuns int `json:"max_benchmark_runs"`
+	// Compilers names the further compiler versions requests may choose, by name
+	Compilers []string `json:"compilers,omitempty"`
+}
+
+// VersionResponse represents the compiler version response
+type VersionResponse struct {
+	Version string `json:"version"`
 }
 
 // HealthResponse represents the health check response
@@ -30,3 +432,19 @@ type HealthResponse struct {
 	Status  string `json:"status"`
 	Service string `json:"service"`
 }
+
+// HealthCheck represents the result of a single readiness check
+type HealthCheck struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"` // pass, fail or skip
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+	CheckedAt string `json:"checked_at"`
+}
+
+// ReadinessResponse represents the readiness check response
+type ReadinessResponse struct {
+	Status  string        `json:"status"`
+	Service string        `json:"service"`
+	Checks  []HealthCheck `json:"checks"`
+}
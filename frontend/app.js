@@ -1,7 +1,7 @@
 // Yz Playground Frontend Application with CodeMirror
 class YzPlayground {
     constructor() {
-        this.apiBase = 'http://localhost:8080/api';
+        this.apiBase = 'http://localhost:8080/api/v1';
         this.codeEditor = null;
         this.outputContent = document.getElementById('output-content');
         this.outputStatus = document.getElementById('output-status');
@@ -61,6 +61,7 @@ class YzPlayground {
             extraKeys: {
                 'Ctrl-Enter': () => this.runCode(),
                 'Cmd-Enter': () => this.runCode(),
+                'Shift-Alt-F': () => this.formatCode(),
                 'Ctrl-/': 'toggleComment',
                 'Cmd-/': 'toggleComment',
                 'Ctrl-K': () => this.clearCode(),
@@ -95,6 +96,7 @@ class YzPlayground {
     bindEvents() {
         // Button events
         document.getElementById('run-btn').addEventListener('click', () => this.runCode());
+        document.getElementById('format-btn').addEventListener('click', () => this.formatCode());
         document.getElementById('copy-link-btn').addEventListener('click', () => this.copyLink());
         document.getElementById('share-btn').addEventListener('click', () => this.shareCode());
         document.getElementById('settings-btn').addEventListener('click', () => this.showSettings());
@@ -289,12 +291,13 @@ main : {
                     
                     this.outputContent.textContent = output;
                 } else {
-                    this.updateStatus('error', 'Execution failed');
+                    this.updateStatus('error', this.errorStatus(result.error_code, 'Execution failed'));
                     this.outputContent.textContent = result.error || 'Unknown error occurred';
                 }
             } else {
-                this.updateStatus('error', 'Request failed');
-                this.outputContent.textContent = result.error || 'Server error occurred';
+                const code = result.error && result.error.code;
+                this.updateStatus('error', this.errorStatus(code, 'Request failed'));
+                this.outputContent.textContent = (result.error && result.error.message) || 'Server error occurred';
             }
         } catch (error) {
             this.updateStatus('error', 'Connection failed');
@@ -304,6 +307,49 @@ main : {
         }
     }
 
+    async formatCode() {
+        const code = this.codeEditor.getValue();
+        if (!code.trim()) {
+            return;
+        }
+
+        try {
+            const response = await fetch(`${this.apiBase}/format`, {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/json',
+                },
+                body: JSON.stringify({ code })
+            });
+
+            const result = await response.json();
+
+            if (!response.ok) {
+                const code = result.error && result.error.code;
+                this.showOutput();
+                this.updateStatus('error', this.errorStatus(code, 'Formatting failed'));
+                this.outputContent.textContent = (result.error && result.error.message) || 'Server error occurred';
+            } else if (!result.success) {
+                // Point the cursor at the syntax error
+                const { line, column } = result.position;
+                this.showOutput();
+                this.updateStatus('error', `Syntax error at line ${line}, column ${column}`);
+                this.outputContent.textContent = result.error;
+                this.codeEditor.setCursor({ line: line - 1, ch: column - 1 });
+                this.codeEditor.focus();
+            } else if (result.changed) {
+                // Formatting keeps line breaks, so the cursor stays on its line
+                const cursor = this.codeEditor.getCursor();
+                this.codeEditor.setValue(result.code);
+                this.codeEditor.setCursor(cursor);
+            }
+        } catch (error) {
+            this.showOutput();
+            this.updateStatus('error', 'Connection failed');
+            this.outputContent.textContent = `Network error: ${error.message}`;
+        }
+    }
+
     clearCode() {
         this.codeEditor.setValue('');
         this.hideOutput();
@@ -356,6 +402,23 @@ main : {
         }
     }
 
+    // Status line for an API error code, or fallback for codes without one
+    errorStatus(code, fallback) {
+        const messages = {
+            SYNTAX_ERROR: 'Syntax error',
+            COMPILE_ERROR: 'Compilation failed',
+            RUNTIME_ERROR: 'Program failed',
+            TIMEOUT: 'Execution timed out',
+            MEMORY_LIMIT: 'Memory limit exceeded',
+            OUTPUT_LIMIT: 'Output limit exceeded',
+            CODE_TOO_LARGE: 'Code is too large',
+            QUEUE_FULL: 'Server is busy, try again shortly',
+            SANDBOX_UNAVAILABLE: 'Sandbox unavailable',
+            RATE_LIMITED: 'Rate limit exceeded'
+        };
+        return messages[code] || fallback;
+    }
+
     shareCode() {
         const code = this.codeEditor.getValue().trim();
         
@@ -64,7 +64,7 @@ export DOCKER_BUILDKIT=0 && export COMPOSE_DOCKER_CLI_BUILD=0 && docker-compose
 
 # Start Podman sandbox
 echo -e "${BLUE}🐳 Starting Podman sandbox...${NC}"
-podman run -d --name yz-sandbox --privileged \
+podman run -d --init --name yz-sandbox --privileged \
   -v $(pwd)/tmp/yz-executions:/tmp/yz-executions \
   localhost/yz-sandbox sleep infinity
 
@@ -95,7 +95,7 @@ fi
 echo -e "${GREEN}✅ Backend container is running${NC}"
 
 # Wait for backend to be ready
-if ! wait_for_service "http://localhost:8080/api/health" "Backend API"; then
+if ! wait_for_service "http://localhost:8080/api/v1/health" "Backend API"; then
     echo -e "${RED}❌ Backend failed to start${NC}"
     podman stop yz-backend 2>/dev/null || true
     podman stop yz-sandbox 2>/dev/null || true
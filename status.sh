@@ -49,7 +49,7 @@ fi
 echo -e "\n${BLUE}🏥 Service Health:${NC}"
 
 # Backend health check
-if curl -s http://localhost:8080/api/health >/dev/null 2>&1; then
+if curl -s http://localhost:8080/api/v1/health >/dev/null 2>&1; then
     echo -e "${GREEN}✅ Backend API: HEALTHY${NC}"
 else
     echo -e "${RED}❌ Backend API: UNHEALTHY${NC}"
@@ -123,9 +123,9 @@ sleep 5
 
 # Verify compiler version
 echo -e "${BLUE}🔍 Verifying compiler version...${NC}"
-if wait_for_service "http://localhost:8080/api/health" "Backend API"; then
+if wait_for_service "http://localhost:8080/api/v1/health" "Backend API"; then
     echo -e "${BLUE}📋 Current compiler version:${NC}"
-    if curl -s http://localhost:8080/api/compiler/version | jq .; then
+    if curl -s http://localhost:8080/api/v1/compiler/version | jq .; then
         echo -e "${GREEN}✅ Compiler version retrieved successfully${NC}"
     else
         echo -e "${YELLOW}⚠️  Could not retrieve compiler version, but service is running${NC}"
@@ -133,7 +133,7 @@ if wait_for_service "http://localhost:8080/api/health" "Backend API"; then
 else
     echo -e "${YELLOW}⚠️  Backend service not responding, but containers may still be starting${NC}"
     echo -e "${YELLOW}   You can check the compiler version later with:${NC}"
-    echo -e "${YELLOW}   curl -s http://localhost:8080/api/compiler/version | jq .${NC}"
+    echo -e "${YELLOW}   curl -s http://localhost:8080/api/v1/compiler/version | jq .${NC}"
 fi
 
 # Show status
@@ -145,7 +145,7 @@ echo -e "${BLUE}🔧 Backend:${NC} http://localhost:8080"
 echo -e "${BLUE}🐳 Sandbox:${NC} yz-sandbox container"
 echo ""
 echo -e "${YELLOW}💡 To check compiler version:${NC}"
-echo -e "   curl -s http://localhost:8080/api/compiler/version | jq ."
+echo -e "   curl -s http://localhost:8080/api/v1/compiler/version | jq ."
 echo ""
 echo -e "${YELLOW}💡 To stop services:${NC} ./stop.sh"
 echo ""